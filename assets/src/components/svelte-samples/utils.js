@@ -5,7 +5,7 @@ if (process.env.ENV === "production") {
 
 const todosReducers = {
     "error": (items, result) => console.error(result),
-    "todos/list": (items, result) =>  [...items, ...result],
+    "todos/list": (items, result) =>  [...items, ...result.todos],
     "todos/insert": (items, result) => [...items, result],
     "todos/update": (items, result) => items.map(item => (item.id === result.id) ? result : item),
     "todos/delete": (items, result) => items.filter(item => item.id !== result.id),
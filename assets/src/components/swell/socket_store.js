@@ -2,6 +2,8 @@ import {writable} from "svelte/store";
 import isEqual from "lodash.isequal";
 
 const reopenTimeouts = [2000, 5000, 10000, 30000, 60000];
+// notification sent by the server when it's draining for a restart
+const reconnectMethod = "server/reconnect";
 
 const jsonRPC2Message = (method, params, id) => {
     if (id) {
@@ -18,6 +20,7 @@ const jsonRPC2Message = (method, params, id) => {
 const createJsonrpc2Socket = (url, socketOptions) => {
     let socket, openPromise, reopenTimeoutHandler;
     let reopenCount = 0;
+    let restarting = false;
     const messageHandlers = new Set();
     const prefixedMessageHandlers = new Map()
 
@@ -45,8 +48,11 @@ const createJsonrpc2Socket = (url, socketOptions) => {
 
     function reOpenSocket() {
         closeSocket();
+        // reconnect quickly but spread out so that all clients don't hit the next instance at once
+        const timeout = restarting ? 250 + Math.random() * 1000 : reopenTimeout();
+        restarting = false;
         if (messageHandlers.size > 0) {
-            reopenTimeoutHandler = setTimeout(() => openSocket(), reopenTimeout());
+            reopenTimeoutHandler = setTimeout(() => openSocket(), timeout);
         }
     }
 
@@ -65,6 +71,10 @@ const createJsonrpc2Socket = (url, socketOptions) => {
 
         socket.onmessage = event => {
             const eventData = JSON.parse(event.data);
+            if (eventData.method === reconnectMethod) {
+                restarting = true;
+                return;
+            }
             if (eventData.id) {
                 let found = false;
                 prefixedMessageHandlers.forEach((messageHandler, prefix) => {
@@ -0,0 +1,14 @@
+import { Controller } from '@hotwired/stimulus'
+
+// removes the element after a delay in milliseconds, e.g. a notification offering to undo a delete.
+export default class extends Controller {
+    static values = { after: Number }
+
+    connect() {
+        this.timeout = setTimeout(() => this.element.remove(), this.afterValue)
+    }
+
+    disconnect() {
+        clearTimeout(this.timeout)
+    }
+}
@@ -1,5 +1,4 @@
 import {Controller} from "@hotwired/stimulus"
-import * as Turbo from "@hotwired/turbo";
 import debounce from "lodash.debounce"
 
 export default class extends Controller {
@@ -11,7 +10,6 @@ export default class extends Controller {
         targets: String,
         template: String,
         params: Object,
-        redirect: String,
         inputDebounce: {type: Number, default: 1000},
     }
 
@@ -33,13 +31,6 @@ export default class extends Controller {
         this.dispatcher = changeRequestsDispatcher(connectURL, [], this.onSocketReconnect)
     }
 
-    connect() {
-        if (this.redirectValue){
-            window.location.href = this.redirectValue
-        }
-    }
-
-
     submit(e) {
         e.preventDefault()
         const {changeRequestId, action, target, targets, template, ...rest} = e.params
@@ -49,7 +40,17 @@ export default class extends Controller {
         }
         let json = {...rest};
         let formData = new FormData(e.currentTarget);
-        formData.forEach((value, key) => json[key] = value);
+        formData.forEach((value, key) => {
+            // the change request fields of forms posted without javascript, see pkg/goliveview/form.go
+            if (key.startsWith("_glv_")) return
+            // fields named like ids[] are sent as an array of all their values, e.g. a multi-select
+            if (key.endsWith("[]")) {
+                key = key.slice(0, -2)
+                json[key] = [...(json[key] || []), value]
+                return
+            }
+            json[key] = value
+        });
         if (this.dispatcher) {
             this.dispatcher(changeRequestId, action, target, targets, template, json)
         }
@@ -63,6 +64,10 @@ export default class extends Controller {
             return
         }
         let json = {...rest};
+        // custom events send their detail along, e.g. sortable:moved
+        if (e instanceof CustomEvent && e.detail) {
+            json = {...json, ...e.detail}
+        }
         if (this.dispatcher) {
             this.dispatcher(changeRequestId, action, target, targets, template, json)
         }
@@ -92,10 +97,19 @@ export default class extends Controller {
 }
 
 const reopenTimeouts = [2000, 5000, 10000, 30000, 60000];
+// close code sent by the server when it's draining for a restart
+const closeServiceRestart = 1012;
+
+// header of the change requests posted for a server-sent events connection, see pkg/goliveview/transport.go
+const connectionHeader = "X-Glv-Connection";
 
 const changeRequestsDispatcher = (url, socketOptions, onSocketReconnect) => {
     let socket, openPromise, reopenTimeoutHandler;
     let reopenCount = 0;
+    // the changes are streamed as server-sent events and the change requests posted
+    // if the websocket never opens, e.g. behind a proxy stripping the Upgrade header.
+    const streamURL = url.replace(/^ws/, "http");
+    let socketOpened = false, eventSource, connectionID, connected, posts = Promise.resolve();
 
     // socket code copied from https://github.com/arlac77/svelte-websocket-store/blob/master/src/index.mjs
     // thank you https://github.com/arlac77 !!
@@ -118,16 +132,18 @@ const changeRequestsDispatcher = (url, socketOptions, onSocketReconnect) => {
         }
     }
 
-    function reOpenSocket() {
+    function reOpenSocket(restarting) {
         closeSocket();
+        // reconnect quickly but spread out so that all clients don't hit the next instance at once
+        const timeout = restarting ? 250 + Math.random() * 1000 : reopenTimeout();
         reopenTimeoutHandler = setTimeout(() => {
 
                 onSocketReconnect()
-                openSocket().then(() => Turbo.session.connectStreamSource(socket)).catch(e => {
+                openSocket().catch(e => {
 
                 })
             },
-            reopenTimeout());
+            timeout);
     }
 
     async function openSocket() {
@@ -144,15 +160,22 @@ const changeRequestsDispatcher = (url, socketOptions, onSocketReconnect) => {
 
         socket = new WebSocket(url, socketOptions);
 
-        socket.onclose = event => reOpenSocket();
+        socket.onmessage = event => messages.receive(event.data);
+        socket.onclose = event => {
+            if (!eventSource) reOpenSocket(event.code === closeServiceRestart);
+        };
 
         openPromise = new Promise((resolve, reject) => {
             socket.onerror = error => {
                 reject(error);
                 openPromise = undefined;
+                if (!socketOpened) openEventSource();
             };
             socket.onopen = event => {
+                socketOpened = true;
                 reopenCount = 0;
+                // a new connection numbers its messages from 1 again
+                messages.reset();
                 resolve();
                 openPromise = undefined;
             };
@@ -160,8 +183,42 @@ const changeRequestsDispatcher = (url, socketOptions, onSocketReconnect) => {
         return openPromise;
     }
 
-    openSocket().then(() => {
-        Turbo.session.connectStreamSource(socket);
+    function openEventSource() {
+        closeSocket();
+        let resolveConnected;
+        const waitConnected = () => connected = new Promise(resolve => resolveConnected = resolve);
+        waitConnected();
+        eventSource = new EventSource(streamURL);
+        eventSource.addEventListener("glv-connect", event => {
+            const reconnected = connectionID !== undefined;
+            connectionID = event.data;
+            messages.reset();
+            resolveConnected();
+            if (reconnected) onSocketReconnect();
+        });
+        eventSource.onmessage = event => messages.receive(event.data);
+        // the EventSource reconnects by itself and gets a new connection id
+        eventSource.onerror = () => waitConnected();
+    }
+
+    // post sends the change requests one after the other to keep their order.
+    function post(changeRequest) {
+        posts = posts
+            .then(() => connected)
+            .then(() => fetch(streamURL, {
+                method: "POST",
+                headers: {"Content-Type": "application/json", [connectionHeader]: connectionID},
+                body: JSON.stringify(changeRequest),
+            }))
+            .then(res => {
+                if (!res.ok) console.error(`glv: posting change request ${changeRequest.id}: ${res.status}`);
+            })
+            .catch(e => console.error(`glv: posting change request ${changeRequest.id}`, e));
+    }
+
+    const messages = messagesApplier();
+    openSocket().catch(e => {
+
     });
     return (id, action, target, targets, template, params) => {
         if (!id) {
@@ -175,8 +232,147 @@ const changeRequestsDispatcher = (url, socketOptions, onSocketReconnect) => {
             template: template,
             params: params
         }
-        const send = () => socket.send(JSON.stringify(changeRequest));
-        if (!socket || socket && socket.readyState !== WebSocket.OPEN) openSocket().then(send);
+        if (eventSource) {
+            post(changeRequest);
+            return;
+        }
+        const send = () => eventSource ? post(changeRequest) : socket.send(JSON.stringify(changeRequest));
+        if (!socket || socket && socket.readyState !== WebSocket.OPEN) openSocket().then(send, () => eventSource && send());
         else send();
     }
-}
\ No newline at end of file
+}
+
+// version of the messages of pkg/goliveview/message.go this controller applies
+const messageVersion = 1;
+
+// messagesApplier applies the actions, commands, events and redirect of the messages received in one animation frame.
+// Messages of another version and the ones older than the last received are dropped.
+const messagesApplier = () => {
+    let lastSeq = 0, pending = [], frame;
+
+    async function apply() {
+        frame = undefined;
+        const received = pending;
+        pending = [];
+        for (const message of received) {
+            (message.actions || []).forEach(applyAction);
+            if (message.commands || message.events) await controllersConnected();
+            (message.commands || []).forEach(applyCommand);
+            (message.events || []).forEach(dispatchEvent);
+            if (message.redirect) {
+                window.location.href = message.redirect;
+                return;
+            }
+        }
+    }
+
+    return {
+        reset() {
+            lastSeq = 0;
+        },
+        receive(data) {
+            let message;
+            try {
+                message = JSON.parse(data);
+            } catch (e) {
+                console.error("glv: parsing message", e);
+                return;
+            }
+            if (message.v !== messageVersion) {
+                console.warn(`glv: ignoring message of version ${message.v}, want ${messageVersion}`);
+                return;
+            }
+            if (message.seq <= lastSeq) return;
+            lastSeq = message.seq;
+            pending.push(message);
+            if (!frame) frame = requestAnimationFrame(apply);
+        },
+    };
+}
+
+// controllersConnected resolves after stimulus has connected the controllers of the elements added by the actions,
+// it does so in a mutation observer callback queued before.
+const controllersConnected = () => new Promise(resolve => queueMicrotask(resolve));
+
+// applyAction applies a turbo stream action to its target or targets.
+function applyAction({action, target, targets, html}) {
+    const elements = targetElements(target, targets);
+    const template = document.createElement("template");
+    template.innerHTML = html || "";
+    for (const el of elements) {
+        const fragment = template.content.cloneNode(true);
+        switch (action) {
+            case "append":
+                removeDuplicateChildren(el, fragment);
+                el.append(fragment);
+                break;
+            case "prepend":
+                removeDuplicateChildren(el, fragment);
+                el.prepend(fragment);
+                break;
+            case "replace":
+                el.replaceWith(fragment);
+                break;
+            case "update":
+                el.replaceChildren(fragment);
+                break;
+            case "before":
+                el.before(fragment);
+                break;
+            case "after":
+                el.after(fragment);
+                break;
+            case "remove":
+                el.remove();
+                break;
+            default:
+                console.error(`glv: unknown action ${action}`);
+        }
+    }
+}
+
+// targetElements returns the element with the id target or the elements matching the selector targets.
+function targetElements(target, targets) {
+    return targets ? [...document.querySelectorAll(targets)] : [document.getElementById(target)].filter(el => el);
+}
+
+// applyCommand runs a command of Session.Exec, see pkg/goliveview/command.go.
+function applyCommand({name, target, targets, value}) {
+    for (const el of targetElements(target, targets)) {
+        switch (name) {
+            case "focus":
+                el.focus();
+                break;
+            case "scroll_into_view":
+                el.scrollIntoView({behavior: "smooth", block: "nearest"});
+                break;
+            case "set_value":
+                el.value = value || "";
+                break;
+            case "reset_form":
+                if (el instanceof HTMLFormElement) el.reset();
+                break;
+            case "toggle_class":
+                el.classList.toggle(value);
+                break;
+            default:
+                console.error(`glv: unknown command ${name}`);
+        }
+    }
+}
+
+// dispatchEvent dispatches an event of Session.PushEvent on its target element or the window.
+function dispatchEvent({name, target, detail}) {
+    const el = target ? document.getElementById(target) : window;
+    if (!el) return;
+    el.dispatchEvent(new CustomEvent(name, {detail: detail, bubbles: true}));
+}
+
+// removeDuplicateChildren removes the children of el replaced by the ones of fragment with the same id, like turbo streams do.
+function removeDuplicateChildren(el, fragment) {
+    for (const child of fragment.children) {
+        if (!child.id) continue;
+        const existing = el.querySelector(`:scope > [id="${CSS.escape(child.id)}"]`);
+        if (existing) existing.remove();
+    }
+}
@@ -0,0 +1,69 @@
+import { Controller } from '@hotwired/stimulus'
+
+// reorders the children of the element by dragging their handle and dispatches sortable:moved
+// with the id of the moved child and of its new neighbours, e.g. {id, prev_id, next_id}.
+export default class extends Controller {
+    static targets = ["handle"]
+    static values = {
+        enabled: Boolean,
+        // stripped from the ids of the children, e.g. todo- of todo-<id>
+        prefix: String,
+    }
+
+    grab(e) {
+        if (!this.enabledValue || !this.handleTargets.some(handle => handle.contains(e.target))) return
+        this.dragged = this.item(e.target)
+        if (this.dragged) this.dragged.draggable = true
+    }
+
+    start(e) {
+        if (!this.dragged) return
+        this.prev = this.dragged.previousElementSibling
+        e.dataTransfer.effectAllowed = "move"
+        // firefox doesn't start a drag without data
+        e.dataTransfer.setData("text/plain", this.dragged.id)
+    }
+
+    over(e) {
+        if (!this.dragged) return
+        e.preventDefault()
+        const item = this.item(e.target)
+        if (!item || item === this.dragged) return
+        const rect = item.getBoundingClientRect()
+        if (e.clientY < rect.top + rect.height / 2) {
+            item.before(this.dragged)
+        } else {
+            item.after(this.dragged)
+        }
+    }
+
+    drop(e) {
+        if (!this.dragged) return
+        e.preventDefault()
+        const prev = this.dragged.previousElementSibling
+        const next = this.dragged.nextElementSibling
+        if (prev === this.prev) return
+        this.dispatch("moved", {
+            detail: {
+                id: this.id(this.dragged),
+                prev_id: this.id(prev),
+                next_id: this.id(next),
+            }
+        })
+    }
+
+    end() {
+        if (this.dragged) this.dragged.draggable = false
+        this.dragged = null
+        this.prev = null
+    }
+
+    item(el) {
+        return [...this.element.children].find(child => child.contains(el))
+    }
+
+    id(el) {
+        if (!el) return ""
+        return el.id.startsWith(this.prefixValue) ? el.id.slice(this.prefixValue.length) : el.id
+    }
+}
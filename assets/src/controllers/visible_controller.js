@@ -0,0 +1,15 @@
+import { Controller } from '@hotwired/stimulus'
+import { useIntersection } from 'stimulus-use'
+
+// clicks the element once it scrolls into view, e.g. to load the next page of an infinite list.
+export default class extends Controller {
+    connect() {
+        useIntersection(this, { element: this.element });
+    }
+
+    appear() {
+        if (this.clicked) return
+        this.clicked = true
+        this.element.click()
+    }
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Profiles
+const (
+	Dev  = "dev"
+	Prod = "prod"
+)
+
+// Database drivers
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+	MySQL    = "mysql"
+)
+
+// What to do about pending migrations at startup
+const (
+	MigrationsRefuse = "refuse"
+	MigrationsApply  = "apply"
+	MigrationsIgnore = "ignore"
+)
+
+// ErrPrintedConfig is returned by Load after printing the config for -print-config, the caller exits.
+var ErrPrintedConfig = errors.New("config printed")
+
+// Config of the app. Values are loaded in order: profile defaults, config file, env vars, flags.
+type Config struct {
+	// Profile picks the defaults, so it can't be changed in the config file.
+	Profile         string        `toml:"-" yaml:"-"`
+	Addr            string        `toml:"addr" yaml:"addr"`
+	RPCAddr         string        `toml:"rpc_addr" yaml:"rpc_addr"`
+	MetricsAddr     string        `toml:"metrics_addr" yaml:"metrics_addr"`
+	DatabaseDriver  string        `toml:"database_driver" yaml:"database_driver"`
+	DatabaseDSN     string        `toml:"database_dsn" yaml:"database_dsn"`
+	Migrations      string        `toml:"migrations" yaml:"migrations"`
+	CookieSecret    string        `toml:"cookie_secret" yaml:"cookie_secret"`
+	TemplatesDir    string        `toml:"templates_dir" yaml:"templates_dir"`
+	AssetsDir       string        `toml:"assets_dir" yaml:"assets_dir"`
+	Debug           bool          `toml:"debug" yaml:"debug"`
+	LogLevel        string        `toml:"log_level" yaml:"log_level"`
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout" yaml:"shutdown_timeout"`
+}
+
+// Defaults returns the config of a profile.
+func Defaults(profile string) Config {
+	c := Config{
+		Profile:        profile,
+		Addr:           ":3000",
+		RPCAddr:        "localhost:3001",
+		MetricsAddr:    "localhost:9090",
+		DatabaseDriver: SQLite,
+		// _time_format=sqlite stores times in a sortable format instead of time.Time.String
+		DatabaseDSN:  "file:app.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite",
+		Migrations:   MigrationsRefuse,
+		TemplatesDir: "templates",
+		AssetsDir:    "public/assets",
+		LogLevel:     "warn",
+		// stays below the kill_timeout of 5s in fly.toml.
+		ShutdownTimeout: 4 * time.Second,
+	}
+	if profile == Dev {
+		c.Debug = true
+		c.Migrations = MigrationsApply
+		c.LogLevel = "debug"
+		c.CookieSecret = "dev-cookie-secret-do-not-use-in-prod"
+	}
+	return c
+}
+
+type field struct {
+	name  string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		{"profile", "config profile: dev or prod", (*stringValue)(&c.Profile)},
+		{"addr", "http listen address", (*stringValue)(&c.Addr)},
+		{"rpc-addr", "net/rpc websocket server listen address", (*stringValue)(&c.RPCAddr)},
+		{"metrics-addr", "private listen address of /metrics, empty disables it", (*stringValue)(&c.MetricsAddr)},
+		{"database-driver", "database driver: sqlite, postgres or mysql", (*stringValue)(&c.DatabaseDriver)},
+		{"database-dsn", "data source name of the database driver, mysql needs parseTime=true", (*stringValue)(&c.DatabaseDSN)},
+		{"migrations", "on pending migrations at startup: refuse, apply or ignore", (*stringValue)(&c.Migrations)},
+		{"cookie-secret", "session cookie signing secret, at least 32 bytes in prod", (*stringValue)(&c.CookieSecret)},
+		{"templates-dir", "templates directory", (*stringValue)(&c.TemplatesDir)},
+		{"assets-dir", "static assets directory served on /static", (*stringValue)(&c.AssetsDir)},
+		{"debug", "log template data and disable the template cache", (*boolValue)(&c.Debug)},
+		{"log-level", "debug, info, warn or error", (*stringValue)(&c.LogLevel)},
+		{"shutdown-timeout", "time to drain connections on shutdown", (*durationValue)(&c.ShutdownTimeout)},
+	}
+}
+
+// envName returns the env var of a flag, e.g. rpc-addr is APP_RPC_ADDR.
+func envName(name string) string {
+	if name == "profile" {
+		return "APP_ENV"
+	}
+	return "APP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load the config from args, env vars and the file in -config or APP_CONFIG.
+// The profile is taken from -profile or APP_ENV and defaults to prod.
+// The args left after the flags are returned, e.g. a subcommand.
+func Load(args []string) (Config, []string, error) {
+	var file string
+	var printConfig bool
+	flags := Defaults(Dev)
+	fs := flag.NewFlagSet("gomodest-template", flag.ContinueOnError)
+	fs.StringVar(&file, "config", os.Getenv("APP_CONFIG"), "optional toml or yaml config `file` (env APP_CONFIG)")
+	fs.BoolVar(&printConfig, "print-config", false, "print the loaded config and exit")
+	for _, f := range flags.fields() {
+		fs.Var(f.value, f.name, fmt.Sprintf("%s (env %s)", f.usage, envName(f.name)))
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s: [flags] [migrate <command>]\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nDefaults above are of the dev profile. Prod defaults:")
+		prod := Defaults(Prod)
+		prod.Print(fs.Output())
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	profile := Prod
+	if env := os.Getenv(envName("profile")); env != "" {
+		profile = env
+	}
+	if set["profile"] {
+		profile = flags.Profile
+	}
+	c := Defaults(profile)
+
+	if file != "" {
+		if err := c.loadFile(file); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	for _, f := range c.fields() {
+		v, ok := os.LookupEnv(envName(f.name))
+		if !ok {
+			continue
+		}
+		if err := f.value.Set(v); err != nil {
+			return Config{}, nil, fmt.Errorf("env %s: %w", envName(f.name), err)
+		}
+	}
+
+	for _, f := range c.fields() {
+		if !set[f.name] {
+			continue
+		}
+		if err := f.value.Set(fs.Lookup(f.name).Value.String()); err != nil {
+			return Config{}, nil, fmt.Errorf("flag -%s: %w", f.name, err)
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	if printConfig {
+		c.Print(os.Stdout)
+		return Config{}, nil, ErrPrintedConfig
+	}
+	return c, fs.Args(), nil
+}
+
+func (c *Config) loadFile(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	switch filepath.Ext(file) {
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(b), c)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", md.Undecoded())
+		}
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, c)
+	default:
+		return fmt.Errorf("config file %s: want a .toml, .yaml or .yml extension", file)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid value.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Profile != Dev && c.Profile != Prod {
+		errs = append(errs, fmt.Errorf("profile: want %s or %s, got %q", Dev, Prod, c.Profile))
+	}
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr: required"))
+	}
+	if c.RPCAddr == "" {
+		errs = append(errs, errors.New("rpc_addr: required"))
+	}
+	if c.MetricsAddr != "" && c.MetricsAddr == c.Addr {
+		errs = append(errs, errors.New("metrics_addr: must differ from addr, /metrics isn't public"))
+	}
+	switch c.DatabaseDriver {
+	case SQLite, Postgres, MySQL:
+	default:
+		errs = append(errs, fmt.Errorf("database_driver: want %s, %s or %s, got %q",
+			SQLite, Postgres, MySQL, c.DatabaseDriver))
+	}
+	if c.DatabaseDSN == "" {
+		errs = append(errs, errors.New("database_dsn: required"))
+	}
+	switch c.Migrations {
+	case MigrationsRefuse, MigrationsApply, MigrationsIgnore:
+	default:
+		errs = append(errs, fmt.Errorf("migrations: want %s, %s or %s, got %q",
+			MigrationsRefuse, MigrationsApply, MigrationsIgnore, c.Migrations))
+	}
+	if c.CookieSecret == "" {
+		errs = append(errs, errors.New("cookie_secret: required"))
+	} else if c.Profile == Prod && len(c.CookieSecret) < 32 {
+		errs = append(errs, errors.New("cookie_secret: want at least 32 bytes in prod"))
+	}
+	if !isDir(c.TemplatesDir) {
+		errs = append(errs, fmt.Errorf("templates_dir: %q is not a directory", c.TemplatesDir))
+	}
+	// assets are built by webpack, in dev they may not exist yet
+	if c.Profile == Prod && !isDir(c.AssetsDir) {
+		errs = append(errs, fmt.Errorf("assets_dir: %q is not a directory", c.AssetsDir))
+	}
+	if _, err := c.Level(); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout: want a positive duration"))
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func isDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
+// Level returns the slog level of LogLevel.
+func (c Config) Level() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+// Print writes the config as flags with the cookie secret redacted.
+func (c Config) Print(w io.Writer) {
+	for _, f := range c.fields() {
+		v := f.value.String()
+		if f.name == "cookie-secret" && v != "" {
+			v = "<redacted>"
+		}
+		fmt.Fprintf(w, "  -%s=%s\n", f.name, v)
+	}
+}
+
+type stringValue string
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
+
+func (s *stringValue) String() string { return string(*s) }
+
+type boolValue bool
+
+func (b *boolValue) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(parsed)
+	return nil
+}
+
+func (b *boolValue) String() string { return strconv.FormatBool(bool(*b)) }
+
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+type durationValue time.Duration
+
+func (d *durationValue) Set(v string) error {
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(parsed)
+	return nil
+}
+
+func (d *durationValue) String() string { return time.Duration(*d).String() }
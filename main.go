@@ -1,57 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"gomodest-template/config"
 	"gomodest-template/samples"
+	"gomodest-template/samples/todos/viewer"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	rl "github.com/adnaan/renderlayout"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) || errors.Is(err, config.ErrPrintedConfig) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("unknown command %q, run with -h for usage", args[0])
+		}
+		exitOnErr(runMigrate(cfg, args[1:]))
+		return
+	}
+	level, _ := cfg.Level()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	// continue traces from incoming requests into the live sockets
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
 
 	index, err := rl.New(
+		rl.TemplatesPath(cfg.TemplatesDir),
 		rl.Layout("index"),
-		rl.DisableCache(true),
-		rl.Debug(true),
+		rl.DisableCache(cfg.Debug),
+		rl.Debug(cfg.Debug),
 		rl.DefaultData(func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
-			return rl.D{
+			d := rl.D{
 				"route":    r.URL.Path,
 				"app_name": "gomodest-template",
-			}, nil
+				"next":     r.URL.Query().Get("next"),
+			}
+			if v, ok := viewer.FromContext(r.Context()); ok {
+				d["viewer"] = v
+			}
+			return d, nil
 		}))
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	r := chi.NewRouter()
 	r.Use(middleware.Compress(5))
 	r.Use(middleware.StripSlashes)
 	r.NotFound(index("404"))
 	r.Get("/", index("home", rl.StaticData(rl.D{"hello": "world"})))
-	r.Route("/samples", samples.Router(index))
-
-	workDir, _ := os.Getwd()
-	public := http.Dir(filepath.Join(workDir, "./", "public", "assets"))
-	staticHandler(r, "/static", public)
-
-	fmt.Println("listening on http://localhost:3000")
-	err = http.ListenAndServe(":3000", r)
+	samplesRouter, samplesShutdown, err := samples.Router(index, cfg, logger)
 	if err != nil {
 		log.Fatal(err)
 	}
+	r.Route("/samples", samplesRouter)
+
+	staticHandler(r, "/static", http.Dir(cfg.AssetsDir))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: cfg.Addr, Handler: r}
+	go func() {
+		fmt.Printf("listening on %s, profile %s\n", cfg.Addr, cfg.Profile)
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	metricsSrv := metricsServer(cfg.MetricsAddr, logger)
+
+	<-ctx.Done()
+	stop()
+	logger.Info("shutting down, draining connections")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	// live sockets and event streams are drained first, http.Server.Shutdown doesn't wait for them
+	if err := samplesShutdown(shutdownCtx); err != nil {
+		logger.Error("samples shutdown", "err", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server shutdown", "err", err)
+	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("metrics server shutdown", "err", err)
+		}
+	}
+	logger.Info("shut down")
+}
+
+// metricsServer serves /metrics on addr, apart from the public router. It returns nil for an empty addr.
+func metricsServer(addr string, logger *slog.Logger) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server", "err", err)
+		}
+	}()
+	return srv
 }
 
 func staticHandler(r chi.Router, path string, root http.FileSystem) {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gomodest-template/config"
+	"gomodest-template/samples"
+	"gomodest-template/samples/todos/migrations"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `Usage: gomodest-template [flags] migrate [-dir dir] [-dev-dsn dsn] <command>
+
+Commands:
+  status      list the migrations and when they were applied
+  up [n]      apply n or all pending migrations
+  down [n]    roll back the last n applied migrations, 1 by default
+  new <name>  generate the next migration of -database-driver from the ent schema diff into -dir
+
+The migrations of each driver are generated separately, postgres and mysql need an empty -dev-dsn database
+to diff against.
+`
+
+// runMigrate runs the migrate subcommand against the configured database.
+func runMigrate(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", "samples/todos/migrations", "migrations source directory used by new")
+	devDSN := fs.String("dev-dsn", "", "empty database used by new, defaults to an in-memory db for sqlite")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	ctx := context.Background()
+	command, args := fs.Arg(0), fs.Args()[1:]
+
+	if command == "new" {
+		if len(args) != 1 {
+			return errors.New("migrate new: want a migration name")
+		}
+		// the diff is taken against the existing migrations, not the configured database
+		scratchCfg := cfg
+		scratchCfg.DatabaseDSN = *devDSN
+		if cfg.DatabaseDriver == config.SQLite && *devDSN == "" {
+			scratchCfg.DatabaseDSN = "file:scratch?mode=memory&_pragma=foreign_keys(1)&_time_format=sqlite"
+		}
+		if scratchCfg.DatabaseDSN == "" {
+			return fmt.Errorf("migrate new: -dev-dsn is required for %s", cfg.DatabaseDriver)
+		}
+		scratch, entDialect, err := samples.OpenDB(scratchCfg)
+		if err != nil {
+			return err
+		}
+		defer scratch.Close()
+		// an in-memory sqlite db lives as long as its connection
+		scratch.SetMaxOpenConns(1)
+		files, err := migrations.Generate(ctx, scratch, entDialect, *dir, args[0])
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("no schema changes")
+		}
+		for _, file := range files {
+			fmt.Println("created", file)
+		}
+		return nil
+	}
+
+	db, entDialect, err := samples.OpenDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := migrations.New(db, entDialect)
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	if len(args) > 0 {
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("migrate %s: want a positive count, got %q", command, args[0])
+		}
+	}
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-40s %s\n", s.Migration, appliedAt)
+		}
+		return nil
+	case "up":
+		applied, err := migrator.Up(ctx, n)
+		for _, m := range applied {
+			fmt.Println("applied", m)
+		}
+		return err
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		for _, m := range rolledBack {
+			fmt.Println("rolled back", m)
+		}
+		return err
+	default:
+		fs.Usage()
+		return fmt.Errorf("migrate: unknown command %q", command)
+	}
+}
+
+func exitOnErr(err error) {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
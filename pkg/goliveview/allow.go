@@ -0,0 +1,79 @@
+package goliveview
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrChangeNotAllowed rejects a change request whose action, target, targets or template
+// isn't declared for it with WithAllowedChanges.
+var ErrChangeNotAllowed = errors.New("change not allowed")
+
+// Changes declares the action, target, targets and template a client may send with a change request.
+// Targets match both target and targets, a trailing * matches any suffix, e.g. todo-*.
+// Handlers aren't limited by it, the templates and targets they set themselves are trusted.
+type Changes struct {
+	Actions   []ActionType
+	Targets   []string
+	Templates []string
+}
+
+// allow validates the client supplied values of a change request against the changes declared for its id.
+// The values of a change request without a declaration are ignored, the values of a rejected one are cleared too.
+func allow(changes map[string]Changes, r *ChangeRequest) (ignored bool, err error) {
+	declared, ok := changes[r.ID]
+	if !ok {
+		ignored = r.Action != "" || r.Target != "" || r.Targets != "" || r.Template != ""
+		r.clearChanges()
+		return ignored, nil
+	}
+	if err := declared.check(r); err != nil {
+		r.clearChanges()
+		return false, err
+	}
+	return false, nil
+}
+
+func (r *ChangeRequest) clearChanges() {
+	r.Action, r.Target, r.Targets, r.Template = "", "", "", ""
+}
+
+func (c Changes) check(r *ChangeRequest) error {
+	if r.Action != "" && !c.allowsAction(r.Action) {
+		return fmt.Errorf("%w: action %q", ErrChangeNotAllowed, r.Action)
+	}
+	for _, target := range []string{r.Target, r.Targets} {
+		if target != "" && !c.allowsTarget(target) {
+			return fmt.Errorf("%w: target %q", ErrChangeNotAllowed, target)
+		}
+	}
+	if r.Template != "" && !contains(c.Templates, r.Template) {
+		return fmt.Errorf("%w: template %q", ErrChangeNotAllowed, r.Template)
+	}
+	return nil
+}
+
+func (c Changes) allowsAction(action ActionType) bool {
+	for _, a := range c.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Changes) allowsTarget(target string) bool {
+	for _, t := range c.Targets {
+		if prefix := strings.TrimSuffix(t, "*"); prefix != t {
+			if strings.HasPrefix(target, prefix) {
+				return true
+			}
+			continue
+		}
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
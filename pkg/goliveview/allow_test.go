@@ -0,0 +1,61 @@
+package goliveview
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllow(t *testing.T) {
+	changes := map[string]Changes{
+		"todos/edit": {
+			Actions:   []ActionType{Replace, Update},
+			Targets:   []string{"todo-*", "todos"},
+			Templates: []string{"todo", "todo-edit"},
+		},
+	}
+	edit := ChangeRequest{ID: "todos/edit", Action: Replace, Target: "todo-42", Targets: "todos", Template: "todo-edit"}
+	cleared := ChangeRequest{ID: "todos/edit"}
+	with := func(f func(r *ChangeRequest)) ChangeRequest {
+		r := edit
+		f(&r)
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		changes map[string]Changes
+		req     ChangeRequest
+		want    ChangeRequest
+		ignored bool
+		err     error
+	}{
+		{"declared", changes, edit, edit, false, nil},
+		{"declared without values", changes, cleared, cleared, false, nil},
+		{"target wildcard", changes, with(func(r *ChangeRequest) { r.Target = "todo-" }), with(func(r *ChangeRequest) { r.Target = "todo-" }), false, nil},
+		{"undeclared id", changes, ChangeRequest{ID: "todos/new", Action: Remove, Target: "todos"}, ChangeRequest{ID: "todos/new"}, true, nil},
+		{"undeclared id without values", changes, ChangeRequest{ID: "todos/new"}, ChangeRequest{ID: "todos/new"}, false, nil},
+		// a view without WithAllowedChanges ignores the values of all its change requests
+		{"no allowed changes", nil, edit, cleared, true, nil},
+		{"disallowed action", changes, with(func(r *ChangeRequest) { r.Action = Remove }), cleared, false, ErrChangeNotAllowed},
+		{"disallowed target", changes, with(func(r *ChangeRequest) { r.Target = "glv-error" }), cleared, false, ErrChangeNotAllowed},
+		{"disallowed targets", changes, with(func(r *ChangeRequest) { r.Targets = "body" }), cleared, false, ErrChangeNotAllowed},
+		{"wildcard is a prefix only", changes, with(func(r *ChangeRequest) { r.Target = "my-todo-42" }), cleared, false, ErrChangeNotAllowed},
+		{"disallowed template", changes, with(func(r *ChangeRequest) { r.Template = "glv-error" }), cleared, false, ErrChangeNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := test.req
+			ignored, err := allow(test.changes, &req)
+			if !errors.Is(err, test.err) || (err == nil) != (test.err == nil) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if ignored != test.ignored {
+				t.Errorf("got ignored %v, want %v", ignored, test.ignored)
+			}
+			if req.ID != test.want.ID || req.Action != test.want.Action || req.Target != test.want.Target ||
+				req.Targets != test.want.Targets || req.Template != test.want.Template {
+				t.Errorf("got %+v, want %+v", req, test.want)
+			}
+		})
+	}
+}
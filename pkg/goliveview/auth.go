@@ -0,0 +1,76 @@
+package goliveview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is returned by an authorizer when the request carries no valid credentials. The request is rejected with 401.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned by an authorizer when the principal isn't allowed to access the view. The request is rejected with 403.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal is the authenticated caller of a view.
+type Principal interface {
+	ID() string
+}
+
+type Authorizer func(r *http.Request) (Principal, error)
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set by the controller's authorizer.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// authorize runs the authorizer and returns the request with the principal set on its context.
+// On failure it writes the 401/403 response and returns nil.
+func authorize(w http.ResponseWriter, r *http.Request, authorizer Authorizer) *http.Request {
+	if authorizer == nil {
+		return r
+	}
+	principal, err := authorizer(r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, ErrForbidden) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, http.StatusText(status), status)
+		return nil
+	}
+	if principal == nil {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil
+	}
+	return r.WithContext(withPrincipal(r.Context(), principal))
+}
+
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package goliveview
+
+// CommandName names a built-in client command, see Session.Exec.
+type CommandName string
+
+const (
+	Focus          CommandName = "focus"
+	ScrollIntoView CommandName = "scroll_into_view"
+	SetValue       CommandName = "set_value"
+	ResetForm      CommandName = "reset_form"
+	ToggleClass    CommandName = "toggle_class"
+)
+
+// Command is run by the client on the element with the id target, or on all the elements matching the
+// css selector targets, e.g. Command{Name: ToggleClass, Targets: ".todo", Value: "is-hidden"}.
+// Value is the value of SetValue and the class of ToggleClass.
+type Command struct {
+	Name    CommandName `json:"name"`
+	Target  string      `json:"target,omitempty"`
+	Targets string      `json:"targets,omitempty"`
+	Value   string      `json:"value,omitempty"`
+}
+
+// FocusCommand focuses the element with the id target, e.g. an input rendered by the same change request.
+func FocusCommand(target string) Command {
+	return Command{Name: Focus, Target: target}
+}
+
+// ScrollIntoViewCommand scrolls the element with the id target into view.
+func ScrollIntoViewCommand(target string) Command {
+	return Command{Name: ScrollIntoView, Target: target}
+}
+
+// SetValueCommand sets the value of the input with the id target.
+func SetValueCommand(target, value string) Command {
+	return Command{Name: SetValue, Target: target, Value: value}
+}
+
+// ResetFormCommand resets the form with the id target.
+func ResetFormCommand(target string) Command {
+	return Command{Name: ResetForm, Target: target}
+}
+
+// ToggleClassCommand toggles class on the element with the id target.
+func ToggleClassCommand(target, class string) Command {
+	return Command{Name: ToggleClass, Target: target, Value: class}
+}
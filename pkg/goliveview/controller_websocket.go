@@ -7,11 +7,22 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/gorilla/securecookie"
 
@@ -25,6 +36,8 @@ import (
 
 type Controller interface {
 	NewView(page string, options ...ViewOption) http.HandlerFunc
+	Presence() Presence
+	Shutdown(ctx context.Context) error
 }
 
 type controlOpt struct {
@@ -32,6 +45,16 @@ type controlOpt struct {
 	subscribeTopicFunc   func(r *http.Request) *string
 	upgrader             websocket.Upgrader
 	enableHTMLFormatting bool
+	authorizer           Authorizer
+	allowedOrigins       []string
+	connRateLimits       rateLimits
+	userRateLimits       rateLimits
+	readLimit            int64
+	maxUserConnections   int
+	metricsRegisterer    prometheus.Registerer
+	tracerProvider       trace.TracerProvider
+	logger               *slog.Logger
+	presence             *presenceOpt
 }
 
 type ControllerOption func(*controlOpt)
@@ -42,6 +65,8 @@ func WithRequestContext(f func(r *http.Request) context.Context) ControllerOptio
 	}
 }
 
+// WithSubscribeTopic subscribes a connection to the topic f returns for its request, nil for none.
+// Sessions send to the connections of a topic with ScopeTopic.
 func WithSubscribeTopic(f func(r *http.Request) *string) ControllerOption {
 	return func(o *controlOpt) {
 		o.subscribeTopicFunc = f
@@ -54,6 +79,74 @@ func WithUpgrader(upgrader websocket.Upgrader) ControllerOption {
 	}
 }
 
+// WithAuthorizer runs the authorizer before a page is rendered or a websocket is upgraded.
+// The returned principal is available to every ChangeRequestHandler through PrincipalFromContext.
+func WithAuthorizer(authorizer Authorizer) ControllerOption {
+	return func(o *controlOpt) {
+		o.authorizer = authorizer
+	}
+}
+
+// WithAllowedOrigins restricts websocket upgrades to the given origins. "*" allows any origin.
+func WithAllowedOrigins(origins ...string) ControllerOption {
+	return func(o *controlOpt) {
+		o.allowedOrigins = origins
+	}
+}
+
+// WithConnectionRateLimit limits the change requests of a single connection.
+// The limit applies to the given change request ids or, if none are given, to all change requests.
+func WithConnectionRateLimit(limit RateLimit, changeRequestIDs ...string) ControllerOption {
+	return func(o *controlOpt) {
+		o.connRateLimits.set(limit, changeRequestIDs)
+	}
+}
+
+// WithUserRateLimit limits the change requests of a user across all of its connections.
+// The limit applies to the given change request ids or, if none are given, to all change requests.
+func WithUserRateLimit(limit RateLimit, changeRequestIDs ...string) ControllerOption {
+	return func(o *controlOpt) {
+		o.userRateLimits.set(limit, changeRequestIDs)
+	}
+}
+
+// WithReadLimit sets the maximum size in bytes of a change request message. The websocket is closed if exceeded,
+// a change request posted for an event stream is rejected with 413.
+func WithReadLimit(readLimit int64) ControllerOption {
+	return func(o *controlOpt) {
+		o.readLimit = readLimit
+	}
+}
+
+// WithMaxConnectionsPerUser rejects websocket upgrades with 429 once a user has n open connections.
+func WithMaxConnectionsPerUser(n int) ControllerOption {
+	return func(o *controlOpt) {
+		o.maxUserConnections = n
+	}
+}
+
+// WithMetricsRegisterer sets the registerer for the controller's metrics. Defaults to prometheus.DefaultRegisterer.
+func WithMetricsRegisterer(registerer prometheus.Registerer) ControllerOption {
+	return func(o *controlOpt) {
+		o.metricsRegisterer = registerer
+	}
+}
+
+// WithTracerProvider sets the provider of the change request spans. Defaults to the global otel provider.
+func WithTracerProvider(tracerProvider trace.TracerProvider) ControllerOption {
+	return func(o *controlOpt) {
+		o.tracerProvider = tracerProvider
+	}
+}
+
+// WithLogger sets the controller's logger. Every line carries the controller, topic, connection,
+// user and change request it belongs to. Defaults to a text logger on stderr at warn level.
+func WithLogger(logger *slog.Logger) ControllerOption {
+	return func(o *controlOpt) {
+		o.logger = logger
+	}
+}
+
 func EnableHTMLFormatting() ControllerOption {
 	return func(o *controlOpt) {
 		o.enableHTMLFormatting = true
@@ -66,26 +159,40 @@ func WebsocketController(name *string, options ...ControllerOption) Controller {
 
 	o := &controlOpt{
 		requestContextFunc: nil,
-		subscribeTopicFunc: func(r *http.Request) *string {
-			challengeKey := r.Header.Get("Sec-Websocket-K")
-			topic := fmt.Sprintf("%s_%s",
-				strings.Replace(r.URL.Path, "/", "_", -1), challengeKey)
-			log.Println("client subscribed to topic", topic)
-			return &topic
-		},
-		upgrader: websocket.Upgrader{},
+		upgrader:           websocket.Upgrader{},
+		readLimit:          1 << 16,
+		metricsRegisterer:  prometheus.DefaultRegisterer,
+		tracerProvider:     otel.GetTracerProvider(),
+		logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		})),
 	}
-
 	for _, option := range options {
 		option(o)
 	}
+	if len(o.allowedOrigins) > 0 {
+		o.upgrader.CheckOrigin = checkOrigin(o.allowedOrigins)
+	}
+	logger := o.logger.With("controller", *name)
+	var presenceTarget string
+	if o.presence != nil {
+		presenceTarget = o.presence.target
+	}
 	return &websocketController{
-		cookieStore:      sessions.NewCookieStore([]byte(securecookie.GenerateRandomKey(32))),
-		topicConnections: make(map[string]map[string]*websocket.Conn),
-		controlOpt:       *o,
-		name:             *name,
+		cookieStore: sessions.NewCookieStore([]byte(securecookie.GenerateRandomKey(32))),
+		scopeConns:  make(map[string]map[string]*conn),
+		sseConns:    make(map[string]*sseTransport),
+		presences:   newPresence(presenceTarget),
+		controlOpt:  *o,
+		name:        *name,
+		metrics:     newMetrics(o.metricsRegisterer),
+		logger:      logger,
 		userSessions: userSessions{
 			stores: make(map[int]SessionStore),
+			logger: logger,
+		},
+		userLimiters: userLimiters{
+			users: make(map[string]*userLimiter),
 		},
 	}
 }
@@ -104,6 +211,7 @@ func (u *userCount) incr() int {
 
 type userSessions struct {
 	stores map[int]SessionStore
+	logger *slog.Logger
 	sync.RWMutex
 }
 
@@ -112,7 +220,7 @@ func (u *userSessions) GetOrCreate(key int) SessionStore {
 	defer u.Unlock()
 	s, ok := u.stores[key]
 	if ok {
-		log.Println("existing user ", key)
+		u.logger.Debug("existing user", "user_id", key)
 		return s
 	}
 	s = &store{
@@ -126,54 +234,171 @@ type websocketController struct {
 	name      string
 	userCount userCount
 	controlOpt
-	cookieStore      *sessions.CookieStore
-	topicConnections map[string]map[string]*websocket.Conn
-	userSessions     userSessions
+	cookieStore *sessions.CookieStore
+	// connections by the key of their scopes
+	scopeConns   map[string]map[string]*conn
+	sseConns     map[string]*sseTransport
+	presences    *presence
+	userSessions userSessions
+	userLimiters userLimiters
+	metrics      *metrics
+	logger       *slog.Logger
+	draining     atomic.Bool
+	active       sync.WaitGroup
+	// drainMu orders the start of draining with the requests Shutdown waits for
+	drainMu sync.Mutex
 	sync.RWMutex
 }
 
-func (wc *websocketController) addConnection(topic, connID string, sess *websocket.Conn) {
+// begin counts a request as active for Shutdown to wait for, it returns false once draining.
+func (wc *websocketController) begin() bool {
+	wc.drainMu.Lock()
+	defer wc.drainMu.Unlock()
+	if wc.draining.Load() {
+		return false
+	}
+	wc.active.Add(1)
+	return true
+}
+
+// client is who a change request is handled for.
+type client struct {
+	conn             *conn
+	user             string
+	store            SessionStore
+	scopeConnections func(scope Scope) []*conn
+	timers           *timers
+	runTimer         func(changeRequest ChangeRequest)
+	connLimiters     *limiters
+	userLimiters     *limiters
+	logger           *slog.Logger
+}
+
+// addConnection registers c under the scope keys, view is the template of its view.
+func (wc *websocketController) addConnection(c *conn, view string, keys []string) {
 	wc.Lock()
 	defer wc.Unlock()
-	_, ok := wc.topicConnections[topic]
-	if !ok {
-		// topic doesn't exit. create
-		wc.topicConnections[topic] = make(map[string]*websocket.Conn)
+	for _, key := range keys {
+		_, ok := wc.scopeConns[key]
+		if !ok {
+			wc.scopeConns[key] = make(map[string]*conn)
+		}
+		wc.scopeConns[key][c.id] = c
+	}
+	wc.metrics.connections.WithLabelValues(wc.name, view).Inc()
+	wc.logger.Debug("addConnection", "view", view, "conn_id", c.id, "scopes", keys)
+}
+
+func (wc *websocketController) removeConnection(c *conn, view string, keys []string) {
+	wc.Lock()
+	defer wc.Unlock()
+	for _, key := range keys {
+		connMap, ok := wc.scopeConns[key]
+		if !ok {
+			continue
+		}
+		delete(connMap, c.id)
+		// no connections for the key, remove it
+		if len(connMap) == 0 {
+			delete(wc.scopeConns, key)
+		}
 	}
-	wc.topicConnections[topic][connID] = sess
-	log.Println("addConnection", topic, connID, len(wc.topicConnections[topic]))
+	c.transport.close()
+	wc.metrics.connections.WithLabelValues(wc.name, view).Dec()
+	wc.logger.Debug("removeConnection", "view", view, "conn_id", c.id)
+}
+
+func (wc *websocketController) addSSEConnection(id string, t *sseTransport) {
+	wc.Lock()
+	defer wc.Unlock()
+	wc.sseConns[id] = t
 }
 
-func (wc *websocketController) removeConnection(topic, connID string) {
+func (wc *websocketController) removeSSEConnection(id string) {
 	wc.Lock()
 	defer wc.Unlock()
-	connMap, ok := wc.topicConnections[topic]
-	if !ok {
+	delete(wc.sseConns, id)
+}
+
+// handlePost queues a change request posted for a server-sent events connection of the same user and view.
+// The changes are streamed to the connection, the response only acknowledges the change request.
+func (wc *websocketController) handlePost(w http.ResponseWriter, r *http.Request, user int) {
+	if !wc.begin() {
+		rejectDraining(w)
 		return
 	}
-	// delete connection from topic
-	conn, ok := connMap[connID]
-	if ok {
-		delete(connMap, connID)
-		conn.Close()
+	defer wc.active.Done()
+
+	if !wc.checkPostOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	wc.RLock()
+	t, ok := wc.sseConns[r.Header.Get(ConnectionHeader)]
+	wc.RUnlock()
+	if !ok || t.user != userKeyOf(r.Context(), user) || t.view != r.URL.Path {
+		http.Error(w, errConnectionClosed.Error(), http.StatusNotFound)
+		return
+	}
+	body := io.Reader(r.Body)
+	if wc.readLimit > 0 {
+		body = http.MaxBytesReader(w, r.Body, wc.readLimit)
+	}
+	message, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
 	}
-	// no connections for the topic, remove it
-	if len(connMap) == 0 {
-		delete(wc.topicConnections, topic)
+	if !t.post(message) {
+		http.Error(w, ErrRateLimited.Error(), http.StatusTooManyRequests)
+		return
 	}
+	w.WriteHeader(http.StatusAccepted)
+}
 
-	log.Println("removeConnection", topic, connID, len(wc.topicConnections[topic]))
+// checkPostOrigin checks the origin of posted change requests like the one of websocket upgrades.
+func (wc *websocketController) checkPostOrigin(r *http.Request) bool {
+	if wc.upgrader.CheckOrigin != nil {
+		return wc.upgrader.CheckOrigin(r)
+	}
+	return sameOrigin(r)
 }
 
-func (wc *websocketController) getTopicConnections(topic string) map[string]*websocket.Conn {
-	wc.Lock()
-	defer wc.Unlock()
-	connMap, ok := wc.topicConnections[topic]
-	if !ok {
-		log.Printf("warn: topic %v doesn't exist\n", topic)
-		return map[string]*websocket.Conn{}
+// requestContext is the context of the change requests of r.
+func (wc *websocketController) requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if wc.requestContextFunc != nil {
+		ctx = wc.requestContextFunc(r)
+	}
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		ctx = withPrincipal(ctx, principal)
+	}
+	return traceContext(ctx, r)
+}
+
+// userKeyOf identifies the user of a connection by its principal or else by its cookie.
+func userKeyOf(ctx context.Context, user int) string {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal.ID()
 	}
-	return connMap
+	return strconv.Itoa(user)
+}
+
+// Presence returns who is connected to the controller's topics.
+func (wc *websocketController) Presence() Presence {
+	return wc.presences
+}
+
+// connections returns the connections of a scope key.
+func (wc *websocketController) connections(key string) []*conn {
+	wc.RLock()
+	defer wc.RUnlock()
+	connMap := wc.scopeConns[key]
+	conns := make([]*conn, 0, len(connMap))
+	for _, c := range connMap {
+		conns = append(conns, c)
+	}
+	return conns
 }
 
 func (wc *websocketController) NewView(page string, options ...ViewOption) http.HandlerFunc {
@@ -233,23 +458,10 @@ func (wc *websocketController) NewView(page string, options ...ViewOption) http.
 		}
 	}
 
-	mountData := make(map[string]interface{})
-	status := 200
-	renderPage := func(w http.ResponseWriter, r *http.Request) {
-
-		if o.onMountFunc != nil {
-			status, mountData = o.onMountFunc(r)
-		}
-		w.WriteHeader(status)
-		if status > 299 {
-			// TODO: custom error page
-			w.Write([]byte(fmt.Sprintf(
-				`<div style="text-align:center"><h1>%d</h1></div>
-<div style="text-align:center"><a href="javascript:history.back()">back</a></div>`, status)))
-			return
-		}
-
-		err = pageTemplate.ExecuteTemplate(w, filepath.Base(o.layout), mountData)
+	writePage := func(w http.ResponseWriter, data M) {
+		renderStart := time.Now()
+		err := pageTemplate.ExecuteTemplate(w, filepath.Base(o.layout), data)
+		wc.metrics.renderDurations.WithLabelValues(wc.name, page).Observe(time.Since(renderStart).Seconds())
 		if err != nil {
 			if errorTemplate != nil {
 				err = errorTemplate.ExecuteTemplate(w, filepath.Base(o.layout), nil)
@@ -262,81 +474,339 @@ func (wc *websocketController) NewView(page string, options ...ViewOption) http.
 		}
 	}
 
-	handleSocket := func(w http.ResponseWriter, r *http.Request, user int) {
-		ctx := r.Context()
-		if wc.requestContextFunc != nil {
-			ctx = wc.requestContextFunc(r)
+	// mount runs the mount func of the view for a request, its data belongs to the request's user only.
+	mount := func(r *http.Request) (int, M) {
+		if o.onMountFunc == nil {
+			return 200, make(M)
+		}
+		status, data := o.onMountFunc(r)
+		if data == nil {
+			data = make(M)
+		}
+		return status, data
+	}
+
+	renderPage := func(w http.ResponseWriter, r *http.Request) {
+		status, mountData := mount(r)
+		w.WriteHeader(status)
+		if status > 299 {
+			writeStatusPage(w, status)
+			return
+		}
+
+		writePage(w, mountData)
+	}
+
+	// runChangeRequest runs the handler of a change request of a client. Its changes, including the error,
+	// are batched in the returned session to be sent at once. It returns the outcome and the error shown to the user.
+	runChangeRequest := func(ctx context.Context, cl client, changeRequest *ChangeRequest) (session, string, string) {
+		reqLogger := cl.logger.With("change_request_id", changeRequest.ID)
+		sess := session{
+			conn:                 cl.conn,
+			batch:                newBatch(),
+			scope:                ScopeConnection,
+			scopeConnections:     cl.scopeConnections,
+			timers:               cl.timers,
+			runTimer:             cl.runTimer,
+			store:                cl.store,
+			rootTemplate:         pageTemplate,
+			changeRequest:        *changeRequest,
+			temporaryKeys:        []string{"action", "target", "targets", "template"},
+			enableHTMLFormatting: wc.enableHTMLFormatting,
+			controllerName:       wc.name,
+			metrics:              wc.metrics,
+			logger:               reqLogger,
+		}
+		changeRequestHandler, ok := o.changeRequestHandlers[changeRequest.ID]
+		if !ok {
+			reqLogger.Warn("no handler found for changeRequest")
+			wc.metrics.changeRequests.WithLabelValues(wc.name, unknownID, outcomeNotFound).Inc()
+			return sess, outcomeNotFound, ""
+		}
+
+		// a view without allowed changes ignores the client values of all its change requests
+		ignored, notAllowed := allow(o.allowedChanges, changeRequest)
+		if ignored {
+			reqLogger.Debug("ignored action, target and template of undeclared changeRequest")
+		}
+		if !changeRequest.timer && (!cl.connLimiters.allow(wc.connRateLimits, changeRequest.ID) ||
+			!cl.userLimiters.allow(wc.userRateLimits, changeRequest.ID)) {
+			sess.setError(ErrRateLimited.Error(),
+				fmt.Errorf("%s: rate limited user %s", changeRequest.ID, cl.user))
+			wc.metrics.changeRequests.WithLabelValues(wc.name, changeRequest.ID, outcomeRateLimited).Inc()
+			return sess, outcomeRateLimited, ErrRateLimited.Error()
+		}
+		if notAllowed != nil {
+			sess.setError(ErrChangeNotAllowed.Error(), fmt.Errorf("%s: %w", changeRequest.ID, notAllowed))
+			wc.metrics.changeRequests.WithLabelValues(wc.name, changeRequest.ID, outcomeNotAllowed).Inc()
+			return sess, outcomeNotAllowed, ErrChangeNotAllowed.Error()
+		}
+
+		spanCtx, span := wc.tracer().Start(ctx, "goliveview.ChangeRequest "+changeRequest.ID,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("goliveview.controller", wc.name),
+				attribute.String("goliveview.change_request.id", changeRequest.ID),
+			))
+		start := time.Now()
+		// a timer doesn't hide the error of the client's last change request
+		if !changeRequest.timer {
+			sess.unsetError()
+		}
+		err := changeRequestHandler(spanCtx, *changeRequest, sess)
+		wc.metrics.changeRequestDurations.WithLabelValues(wc.name, changeRequest.ID).Observe(time.Since(start).Seconds())
+		outcome := outcomeOK
+		if err != nil {
+			outcome = outcomeError
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		wc.metrics.changeRequests.WithLabelValues(wc.name, changeRequest.ID, outcome).Inc()
+		if err != nil {
+			userMessage := "internal error"
+			if userError := errors.Unwrap(err); userError != nil {
+				userMessage = userError.Error()
+			}
+			sess.setError(userMessage, err)
+			return sess, outcome, userMessage
+		}
+		return sess, outcome, ""
+	}
+
+	// handleForm runs the change request of a form posted without javascript, e.g. when it failed to load.
+	// Turbo gets the changes as a turbo stream, a browser the page rendered with the state they've set.
+	handleForm := func(w http.ResponseWriter, r *http.Request, user int) {
+		if !wc.begin() {
+			rejectDraining(w)
+			return
+		}
+		defer wc.active.Done()
+
+		if !wc.checkPostOrigin(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if wc.readLimit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, wc.readLimit)
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		changeRequest, err := formChangeRequest(r.PostForm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := wc.requestContext(r)
+		userKey := userKeyOf(ctx, user)
+		view := r.URL.Path
+		logger := wc.logger.With("user_id", userKey, "view", view)
+		// a posted form counts towards the user's rate limits but not their connections
+		userLimiters := wc.userLimiters.post(userKey)
+		defer wc.userLimiters.postDone(userKey)
+
+		store := &recordingStore{SessionStore: wc.userSessions.GetOrCreate(user), set: make(M)}
+		sess, outcome, userMessage := runChangeRequest(ctx, client{
+			conn:  &conn{id: shortuuid.New(), transport: formTransport{}},
+			user:  userKey,
+			store: store,
+			scopeConnections: func(scope Scope) []*conn {
+				return wc.connections(scope.key(view, userKey))
+			},
+			connLimiters: newLimiters(),
+			userLimiters: userLimiters,
+			logger:       logger,
+		}, &changeRequest)
+		m := sess.respond()
+
+		if m.Redirect != "" {
+			http.Redirect(w, r, m.Redirect, http.StatusSeeOther)
+			return
+		}
+		status := formStatus(outcome)
+		if acceptsTurboStream(r) {
+			w.Header().Set("Content-Type", turboStreamContentType)
+			w.WriteHeader(status)
+			if err := writeTurboStream(w, m); err != nil {
+				logger.Debug("writing turbo stream", "err", err)
+			}
+			return
+		}
+
+		mountStatus, data := mount(r)
+		if mountStatus > 299 {
+			w.WriteHeader(mountStatus)
+			writeStatusPage(w, mountStatus)
+			return
+		}
+		for k, v := range store.set {
+			data[k] = v
+		}
+		if userMessage != "" {
+			data["error"] = userMessage
+		}
+		w.WriteHeader(status)
+		writePage(w, data)
+	}
+
+	// handleConn serves a websocket connection or, with sse, a server-sent events one fed by handlePost.
+	handleConn := func(w http.ResponseWriter, r *http.Request, user int, sse bool) {
+		if !wc.begin() {
+			rejectDraining(w)
+			return
 		}
+		defer wc.active.Done()
+
+		ctx := wc.requestContext(r)
 		var topic *string
 		if wc.subscribeTopicFunc != nil {
 			topic = wc.subscribeTopicFunc(r)
 		}
 
-		c, err := wc.upgrader.Upgrade(w, r, nil)
+		userKey := userKeyOf(ctx, user)
+		connID := shortuuid.New()
+		view := r.URL.Path
+		logger := wc.logger.With("conn_id", connID, "user_id", userKey, "view", view)
+		if topic != nil {
+			logger = logger.With("topic", *topic)
+		}
+
+		// the session store starts with the mount data of the connection's own request
+		mountStatus, mountData := mount(r)
+		if mountStatus > 299 {
+			logger.Warn("rejected connection", "mount_status", mountStatus)
+			http.Error(w, http.StatusText(mountStatus), mountStatus)
+			return
+		}
+
+		userLimiters, err := wc.userLimiters.connect(userKey, wc.maxUserConnections)
 		if err != nil {
+			logger.Warn("rejected connection", "err", err)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
 			return
 		}
-		defer c.Close()
+		defer wc.userLimiters.disconnect(userKey)
+		connLimiters := newLimiters()
+
+		var c *conn
+		var receive func() ([]byte, error)
+		if sse {
+			t := newSSETransport(w, userKey, view)
+			if err := t.open(connID); err != nil {
+				logger.Warn("opening event stream failed", "err", err)
+				return
+			}
+			go t.keepAlive()
+			wc.addSSEConnection(connID, t)
+			defer wc.removeSSEConnection(connID)
+			c = &conn{id: connID, transport: t}
+			receive = func() ([]byte, error) {
+				return t.receive(r.Context())
+			}
+		} else {
+			ws, err := wc.upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				logger.Warn("upgrade failed", "err", err)
+				return
+			}
+			defer ws.Close()
+			if wc.readLimit > 0 {
+				ws.SetReadLimit(wc.readLimit)
+			}
+			c = &conn{id: connID, transport: websocketTransport{ws: ws}}
+			receive = func() ([]byte, error) {
+				_, message, err := ws.ReadMessage()
+				return message, err
+			}
+		}
 
-		connID := shortuuid.New()
 		store := wc.userSessions.GetOrCreate(user)
 		store.Set(mountData)
+		scopeKeys := []string{ScopeUser.key(view, userKey), ScopeView.key(view, userKey)}
 		if topic != nil {
-			wc.addConnection(*topic, connID, c)
+			scopeKeys = append(scopeKeys, ScopeTopic(*topic).key(view, userKey))
+		}
+		// the path of a view may contain ids, e.g. /todos/{id}/edit, its template labels the metrics
+		wc.addConnection(c, page, scopeKeys)
+		defer wc.removeConnection(c, page, scopeKeys)
+		if topic != nil && wc.presence != nil {
+			defer wc.joinPresence(r, pageTemplate, *topic, userKey, c, logger)()
+		}
+		timers := newTimers()
+		defer timers.stop()
+		cl := client{
+			conn:   c,
+			user:   userKey,
+			store:  store,
+			timers: timers,
+			scopeConnections: func(scope Scope) []*conn {
+				return wc.connections(scope.key(view, userKey))
+			},
+			connLimiters: connLimiters,
+			userLimiters: userLimiters,
+			logger:       logger,
 		}
+		cl.runTimer = func(changeRequest ChangeRequest) {
+			// the connection is asked to reconnect elsewhere
+			if wc.draining.Load() {
+				return
+			}
+			sess, _, _ := runChangeRequest(ctx, cl, &changeRequest)
+			sess.flush()
+		}
+
+		// the change requests of the client and the timers are run one at a time
+		received := make(chan []byte)
+		go func() {
+			defer close(received)
+			for {
+				message, err := receive()
+				if err != nil {
+					logger.Debug("connection closed", "err", err)
+					return
+				}
+				received <- message
+			}
+		}()
 	loop:
 		for {
-			mt, message, err := c.ReadMessage()
-			if err != nil {
-				log.Println("readx:", err)
-				break loop
+			var message []byte
+			select {
+			case run := <-timers.fired:
+				run()
+				continue
+			case m, ok := <-received:
+				if !ok {
+					break loop
+				}
+				message = m
 			}
 
 			changeRequest := new(ChangeRequest)
 			err = json.NewDecoder(bytes.NewReader(message)).Decode(changeRequest)
 			if err != nil {
-				log.Printf("err: parsing changeRequest, msg %s \n", string(message))
+				logger.Warn("parsing changeRequest", "err", err, "message", string(message))
 				continue
 			}
 
 			if changeRequest.ID == "" {
-				log.Printf("err: changeRequest %v, field changeRequest.id is required\n", changeRequest)
-				continue
-			}
-
-			changeRequestHandler, ok := o.changeRequestHandlers[changeRequest.ID]
-			if !ok {
-				log.Printf("err: no handler found for changeRequest %s\n", changeRequest.ID)
+				logger.Warn("field changeRequest.id is required", "message", string(message))
 				continue
 			}
 
-			sess := session{
-				messageType:          mt,
-				conns:                wc.getTopicConnections(*topic),
-				store:                store,
-				rootTemplate:         pageTemplate,
-				changeRequest:        *changeRequest,
-				temporaryKeys:        []string{"action", "target", "targets", "template"},
-				enableHTMLFormatting: wc.enableHTMLFormatting,
-			}
-			sess.unsetError()
-			err = changeRequestHandler(ctx, *changeRequest, sess)
-			if err != nil {
-				log.Printf("%s: err: %v\n", changeRequest.ID, err)
-				userMessage := "internal error"
-				if userError := errors.Unwrap(err); userError != nil {
-					userMessage = userError.Error()
-				}
-				sess.setError(userMessage, err)
-			}
-		}
-
-		if topic != nil {
-			wc.removeConnection(*topic, connID)
+			sess, _, _ := runChangeRequest(ctx, cl, changeRequest)
+			sess.flush()
 		}
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		r = authorize(w, r, wc.authorizer)
+		if r == nil {
+			return
+		}
 		name := strings.TrimSpace(wc.name)
 		wc.cookieStore.MaxAge(0)
 		cookieSession, _ := wc.cookieStore.Get(r, fmt.Sprintf("_glv_key_%s", name))
@@ -352,9 +822,16 @@ func (wc *websocketController) NewView(page string, options ...ViewOption) http.
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		if r.Header.Get("Connection") == "Upgrade" && r.Header.Get("Upgrade") == "websocket" {
-			handleSocket(w, r, user.(int))
-		} else {
+		switch {
+		case r.Header.Get("Connection") == "Upgrade" && r.Header.Get("Upgrade") == "websocket":
+			handleConn(w, r, user.(int), false)
+		case acceptsEventStream(r):
+			handleConn(w, r, user.(int), true)
+		case r.Method == http.MethodPost && r.Header.Get(ConnectionHeader) != "":
+			wc.handlePost(w, r, user.(int))
+		case r.Method == http.MethodPost:
+			handleForm(w, r, user.(int))
+		default:
 			renderPage(w, r)
 		}
 	}
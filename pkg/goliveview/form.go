@@ -0,0 +1,125 @@
+package goliveview
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The fields of a change request posted by a form without javascript, e.g.
+//
+//	<form method="post" data-action="glv#submit" ...>
+//	    <input type="hidden" name="_glv_change_request_id" value="insert">
+//
+// The other fields are the params, fields named like ids[] are sent as an array of all their values.
+const (
+	FormChangeRequestID = "_glv_change_request_id"
+	FormAction          = "_glv_action"
+	FormTarget          = "_glv_target"
+	FormTargets         = "_glv_targets"
+	FormTemplate        = "_glv_template"
+)
+
+// formFieldPrefix starts the fields of a posted form which aren't params.
+const formFieldPrefix = "_glv_"
+
+const turboStreamContentType = "text/vnd.turbo-stream.html"
+
+var errFormChangeRequestID = fmt.Errorf("form field %s is required", FormChangeRequestID)
+
+// formChangeRequest reads the change request of a posted form.
+func formChangeRequest(form url.Values) (ChangeRequest, error) {
+	changeRequest := ChangeRequest{
+		ID:       form.Get(FormChangeRequestID),
+		Action:   ActionType(form.Get(FormAction)),
+		Target:   form.Get(FormTarget),
+		Targets:  form.Get(FormTargets),
+		Template: form.Get(FormTemplate),
+	}
+	if changeRequest.ID == "" {
+		return changeRequest, errFormChangeRequestID
+	}
+	params := make(map[string]interface{})
+	for key, values := range form {
+		if strings.HasPrefix(key, formFieldPrefix) || len(values) == 0 {
+			continue
+		}
+		if strings.HasSuffix(key, "[]") {
+			params[strings.TrimSuffix(key, "[]")] = values
+			continue
+		}
+		params[key] = values[0]
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return changeRequest, err
+	}
+	changeRequest.Params = data
+	return changeRequest, nil
+}
+
+// recordingStore keeps what a form's change request handler sets, the page rendered in response shows it.
+type recordingStore struct {
+	SessionStore
+	mu  sync.Mutex
+	set M
+}
+
+func (s *recordingStore) Set(m M) error {
+	s.mu.Lock()
+	for k, v := range m {
+		s.set[k] = v
+	}
+	s.mu.Unlock()
+	return s.SessionStore.Set(m)
+}
+
+// formTransport is the connection of a posted form. Its batched message is the response, anything sent
+// afterwards, e.g. the removal of a flash, is dropped.
+type formTransport struct{}
+
+func (formTransport) write(data []byte) error          { return nil }
+func (formTransport) restart(deadline time.Time) error { return nil }
+func (formTransport) close() error                     { return nil }
+
+func acceptsTurboStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), turboStreamContentType)
+}
+
+// writeTurboStream writes the actions of a message as turbo stream elements.
+func writeTurboStream(w io.Writer, m message) error {
+	for _, a := range m.Actions {
+		target := fmt.Sprintf(`target="%s"`, html.EscapeString(a.Target))
+		if a.Targets != "" {
+			target = fmt.Sprintf(`targets="%s"`, html.EscapeString(a.Targets))
+		}
+		_, err := fmt.Fprintf(w, "<turbo-stream action=\"%s\" %s><template>%s</template></turbo-stream>\n",
+			html.EscapeString(string(a.Action)), target, a.HTML)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formStatus is the status of the response to a posted form, 422 tells turbo to render a failed submission.
+func formStatus(outcome string) int {
+	switch outcome {
+	case outcomeOK:
+		return http.StatusOK
+	case outcomeNotFound:
+		return http.StatusNotFound
+	case outcomeNotAllowed:
+		return http.StatusForbidden
+	case outcomeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
@@ -0,0 +1,88 @@
+package goliveview
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// messageVersion is the version of the message format, clients ignore the messages of other versions.
+const messageVersion = 1
+
+// message is sent to a connection in one websocket frame, the client applies its actions at once.
+// Seq counts the messages of the connection from 1.
+type message struct {
+	V        int             `json:"v"`
+	Seq      uint64          `json:"seq"`
+	Actions  []messageAction `json:"actions,omitempty"`
+	Commands []Command       `json:"commands,omitempty"`
+	Events   []event         `json:"events,omitempty"`
+	Redirect string          `json:"redirect,omitempty"`
+}
+
+// messageAction is rendered by the client as a turbo stream action.
+type messageAction struct {
+	Action  ActionType `json:"action"`
+	Target  string     `json:"target,omitempty"`
+	Targets string     `json:"targets,omitempty"`
+	HTML    string     `json:"html,omitempty"`
+}
+
+// event is dispatched by the client on the element with the id target, or the window without one.
+type event struct {
+	Name   string      `json:"name"`
+	Target string      `json:"target,omitempty"`
+	Detail interface{} `json:"detail,omitempty"`
+}
+
+func (m message) empty() bool {
+	return len(m.Actions) == 0 && len(m.Commands) == 0 && len(m.Events) == 0 && m.Redirect == ""
+}
+
+// send numbers and writes a message, it returns the size of the frame.
+func (c *conn) send(m message) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	m.V = messageVersion
+	m.Seq = c.seq
+	data, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), c.transport.write(data)
+}
+
+// batch collects the messages of a change request by recipient, they are sent when its handler returns.
+type batch struct {
+	mu       sync.Mutex
+	conns    []*conn
+	messages map[*conn]*message
+}
+
+func newBatch() *batch {
+	return &batch{messages: make(map[*conn]*message)}
+}
+
+// add applies f to the message of each of the connections.
+func (b *batch) add(conns []*conn, f func(m *message)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range conns {
+		m, ok := b.messages[c]
+		if !ok {
+			m = new(message)
+			b.messages[c] = m
+			b.conns = append(b.conns, c)
+		}
+		f(m)
+	}
+}
+
+// take empties the batch and returns its connections in the order they were added with their message.
+func (b *batch) take() ([]*conn, map[*conn]*message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	conns, messages := b.conns, b.messages
+	b.conns, b.messages = nil, make(map[*conn]*message)
+	return conns, messages
+}
@@ -0,0 +1,94 @@
+package goliveview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "gomodest-template/pkg/goliveview"
+
+type metrics struct {
+	connections            *prometheus.GaugeVec
+	changeRequests         *prometheus.CounterVec
+	changeRequestDurations *prometheus.HistogramVec
+	renderDurations        *prometheus.HistogramVec
+	sentBytes              *prometheus.CounterVec
+}
+
+func newMetrics(registerer prometheus.Registerer) *metrics {
+	m := &metrics{
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "goliveview",
+			Name:      "connections_active",
+			Help:      "Number of open websocket connections and event streams per view template.",
+		}, []string{"controller", "view"}),
+		changeRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "goliveview",
+			Name:      "change_requests_total",
+			Help:      "Number of change requests by id and outcome.",
+		}, []string{"controller", "id", "outcome"}),
+		changeRequestDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "goliveview",
+			Name:      "change_request_duration_seconds",
+			Help:      "Latency of change request handlers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"controller", "id"}),
+		renderDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "goliveview",
+			Name:      "template_render_duration_seconds",
+			Help:      "Time taken to execute page and change templates.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"controller", "template"}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "goliveview",
+			Name:      "sent_bytes_total",
+			Help:      "Bytes written to websocket connections.",
+		}, []string{"controller"}),
+	}
+	m.connections = register(registerer, m.connections).(*prometheus.GaugeVec)
+	m.changeRequests = register(registerer, m.changeRequests).(*prometheus.CounterVec)
+	m.changeRequestDurations = register(registerer, m.changeRequestDurations).(*prometheus.HistogramVec)
+	m.renderDurations = register(registerer, m.renderDurations).(*prometheus.HistogramVec)
+	m.sentBytes = register(registerer, m.sentBytes).(*prometheus.CounterVec)
+	return m
+}
+
+// register returns the already registered collector when several controllers share a registerer.
+func register(registerer prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	err := registerer.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector
+	}
+	panic(err)
+}
+
+// Change request outcomes
+const (
+	outcomeOK          = "ok"
+	outcomeError       = "error"
+	outcomeRateLimited = "rate_limited"
+	outcomeNotFound    = "not_found"
+	outcomeNotAllowed  = "not_allowed"
+)
+
+// unknownID labels the change requests without a handler, their ids come from the client.
+const unknownID = "unknown"
+
+// traceContext continues the trace of the http request which upgraded the socket.
+func traceContext(ctx context.Context, r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+func (wc *websocketController) tracer() trace.Tracer {
+	return wc.tracerProvider.Tracer(instrumentationName)
+}
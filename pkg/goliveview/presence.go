@@ -0,0 +1,195 @@
+package goliveview
+
+import (
+	"bytes"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// Presence tracks who is connected to the topics of a controller, see WithPresence.
+type Presence interface {
+	// List returns the users connected to topic in the order they joined.
+	List(topic string) []Present
+}
+
+// Present is a user connected to a topic.
+type Present struct {
+	// ID of the element the user is rendered in, the presence target and the key.
+	ID  string
+	Key string
+	// Metas of each of the user's connections, in the order they joined.
+	Metas []M
+}
+
+type presenceOpt struct {
+	target   string
+	template string
+	meta     func(r *http.Request) M
+}
+
+// WithPresence tracks the connections of the topics of WithSubscribeTopic by user, with the meta f returns for
+// their request, e.g. the user's name. A connection joining a topic gets everyone present rendered into the
+// element with the id target, a Present each with the template named template. The other connections get
+// the joins and leaves: a user is appended when their first connection joins, replaced when the metas change
+// and removed when their last connection leaves.
+//
+// The presence of a node is in-memory: its connections reconnect after a restart and get everyone present
+// again, leaves aren't sent while the controller shuts down.
+func WithPresence(target, template string, f func(r *http.Request) M) ControllerOption {
+	return func(o *controlOpt) {
+		o.presence = &presenceOpt{target: target, template: template, meta: f}
+	}
+}
+
+type presentConn struct {
+	key    string
+	connID string
+	meta   M
+}
+
+// presence is the in-memory registry of the connections present in each topic.
+type presence struct {
+	target string
+	mu     sync.RWMutex
+	topics map[string][]presentConn
+}
+
+func newPresence(target string) *presence {
+	return &presence{target: target, topics: make(map[string][]presentConn)}
+}
+
+func (p *presence) List(topic string) []Present {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.list(topic)
+}
+
+func (p *presence) list(topic string) []Present {
+	var presents []Present
+	index := make(map[string]int)
+	for _, pc := range p.topics[topic] {
+		i, ok := index[pc.key]
+		if !ok {
+			i = len(presents)
+			index[pc.key] = i
+			presents = append(presents, Present{ID: p.target + "-" + pc.key, Key: pc.key})
+		}
+		presents[i].Metas = append(presents[i].Metas, pc.meta)
+	}
+	return presents
+}
+
+// present returns the user of key in topic, false if none of their connections is left.
+func (p *presence) present(topic, key string) (Present, bool) {
+	for _, present := range p.list(topic) {
+		if present.Key == key {
+			return present, true
+		}
+	}
+	return Present{ID: p.target + "-" + key, Key: key}, false
+}
+
+// join adds a connection to topic. It returns the user's presence and whether it's their first connection.
+func (p *presence) join(topic, key, connID string, meta M) (Present, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, present := p.present(topic, key)
+	p.topics[topic] = append(p.topics[topic], presentConn{key: key, connID: connID, meta: meta})
+	joined, _ := p.present(topic, key)
+	return joined, !present
+}
+
+// leave removes a connection from topic. It returns the user's presence and whether it was their last connection.
+func (p *presence) leave(topic, key, connID string) (Present, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.topics[topic]
+	for i, pc := range conns {
+		if pc.connID == connID {
+			conns = append(conns[:i:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(p.topics, topic)
+	} else {
+		p.topics[topic] = conns
+	}
+	left, present := p.present(topic, key)
+	return left, !present
+}
+
+// renderPresents renders presents with the presence template of a view.
+func renderPresents(t *template.Template, name string, presents ...Present) (string, error) {
+	var b bytes.Buffer
+	for _, present := range presents {
+		if err := t.ExecuteTemplate(&b, name, present); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// presenceDiff sends a change of the presence of a topic to its connections but the one it's about.
+func (wc *websocketController) presenceDiff(topic string, except *conn, action messageAction) {
+	for _, c := range wc.connections(ScopeTopic(topic).key("", "")) {
+		if c == except {
+			continue
+		}
+		wc.sendMessage(c, message{Actions: []messageAction{action}})
+	}
+}
+
+// sendMessage sends a message outside of a change request.
+func (wc *websocketController) sendMessage(c *conn, m message) {
+	n, err := c.send(m)
+	if err != nil {
+		wc.logger.Warn("writing message, closing conn", "to_conn_id", c.id, "err", err)
+		c.transport.close()
+		return
+	}
+	wc.metrics.sentBytes.WithLabelValues(wc.name).Add(float64(n))
+}
+
+// joinPresence tracks a connection subscribed to topic and sends it everyone present, the returned func untracks it.
+func (wc *websocketController) joinPresence(r *http.Request, t *template.Template, topic, key string, c *conn,
+	logger *slog.Logger) func() {
+	var meta M
+	if wc.presence.meta != nil {
+		meta = wc.presence.meta(r)
+	}
+	name := wc.presence.template
+	joined, first := wc.presences.join(topic, key, c.id, meta)
+	if html, err := renderPresents(t, name, wc.presences.List(topic)...); err != nil {
+		logger.Warn("rendering presence", "err", err)
+	} else {
+		wc.sendMessage(c, message{Actions: []messageAction{{Action: Update, Target: wc.presence.target, HTML: html}}})
+	}
+	if html, err := renderPresents(t, name, joined); err != nil {
+		logger.Warn("rendering presence", "err", err)
+	} else if first {
+		wc.presenceDiff(topic, c, messageAction{Action: Append, Target: wc.presence.target, HTML: html})
+	} else {
+		wc.presenceDiff(topic, c, messageAction{Action: Replace, Target: joined.ID, HTML: html})
+	}
+
+	return func() {
+		left, last := wc.presences.leave(topic, key, c.id)
+		// the connections come back after the restart
+		if wc.draining.Load() {
+			return
+		}
+		if last {
+			wc.presenceDiff(topic, c, messageAction{Action: Remove, Target: left.ID})
+			return
+		}
+		html, err := renderPresents(t, name, left)
+		if err != nil {
+			logger.Warn("rendering presence", "err", err)
+			return
+		}
+		wc.presenceDiff(topic, c, messageAction{Action: Replace, Target: left.ID, HTML: html})
+	}
+}
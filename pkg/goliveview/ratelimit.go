@@ -0,0 +1,154 @@
+package goliveview
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrRateLimited is shown to the user when a connection or user sends change requests faster than allowed.
+	ErrRateLimited = errors.New("too many requests, please slow down")
+	// ErrTooManyConnections rejects a websocket upgrade when the user has reached the maximum number of connections.
+	ErrTooManyConnections = errors.New("too many connections")
+)
+
+// RateLimit is a token bucket which allows Burst change requests at once and refills one every Interval.
+type RateLimit struct {
+	Interval time.Duration
+	Burst    int
+}
+
+type rateLimits struct {
+	all  *RateLimit
+	byID map[string]RateLimit
+}
+
+func (r *rateLimits) set(limit RateLimit, changeRequestIDs []string) {
+	if len(changeRequestIDs) == 0 {
+		r.all = &limit
+		return
+	}
+	if r.byID == nil {
+		r.byID = make(map[string]RateLimit)
+	}
+	for _, id := range changeRequestIDs {
+		r.byID[id] = limit
+	}
+}
+
+// bucket returns the limit for the change request id and the key of the bucket it's counted in.
+// A limit set for the id takes precedence over the limit for all change requests.
+func (r rateLimits) bucket(id string) (RateLimit, string, bool) {
+	if limit, ok := r.byID[id]; ok {
+		return limit, id, true
+	}
+	if r.all != nil {
+		return *r.all, "*", true
+	}
+	return RateLimit{}, "", false
+}
+
+type limiters struct {
+	buckets map[string]*rate.Limiter
+	sync.Mutex
+}
+
+func newLimiters() *limiters {
+	return &limiters{buckets: make(map[string]*rate.Limiter)}
+}
+
+func (l *limiters) allow(limits rateLimits, id string) bool {
+	limit, key, ok := limits.bucket(id)
+	if !ok {
+		return true
+	}
+	l.Lock()
+	defer l.Unlock()
+	limiter, ok := l.buckets[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(limit.Interval), limit.Burst)
+		l.buckets[key] = limiter
+	}
+	return limiter.Allow()
+}
+
+// userLimiterTTL is how long a user's buckets are kept after its last connection is closed.
+var userLimiterTTL = time.Minute
+
+type userLimiter struct {
+	conns int
+	// posts are the forms posted without javascript in flight, they share the buckets but aren't connections.
+	posts    int
+	lastSeen time.Time
+	limiters *limiters
+}
+
+// userLimiters tracks the connections and the rate limit buckets of each user.
+type userLimiters struct {
+	users map[string]*userLimiter
+	sync.Mutex
+}
+
+func (u *userLimiters) connect(user string, maxConnections int) (*limiters, error) {
+	u.Lock()
+	defer u.Unlock()
+	u.prune()
+	ul, ok := u.users[user]
+	if !ok {
+		ul = &userLimiter{limiters: newLimiters()}
+		u.users[user] = ul
+	}
+	if maxConnections > 0 && ul.conns >= maxConnections {
+		return nil, ErrTooManyConnections
+	}
+	ul.conns++
+	return ul.limiters, nil
+}
+
+// post returns the buckets of user for a posted form, it doesn't count towards the user's connections.
+func (u *userLimiters) post(user string) *limiters {
+	u.Lock()
+	defer u.Unlock()
+	u.prune()
+	ul, ok := u.users[user]
+	if !ok {
+		ul = &userLimiter{limiters: newLimiters()}
+		u.users[user] = ul
+	}
+	ul.posts++
+	return ul.limiters
+}
+
+func (u *userLimiters) postDone(user string) {
+	u.Lock()
+	defer u.Unlock()
+	ul, ok := u.users[user]
+	if !ok {
+		return
+	}
+	ul.posts--
+	ul.lastSeen = time.Now()
+}
+
+func (u *userLimiters) disconnect(user string) {
+	u.Lock()
+	defer u.Unlock()
+	ul, ok := u.users[user]
+	if !ok {
+		return
+	}
+	ul.conns--
+	ul.lastSeen = time.Now()
+}
+
+// prune releases the buckets of users without connections or posts once userLimiterTTL has passed.
+func (u *userLimiters) prune() {
+	for user, ul := range u.users {
+		if ul.conns <= 0 && ul.posts <= 0 && time.Since(ul.lastSeen) > userLimiterTTL {
+			delete(u.users, user)
+		}
+	}
+}
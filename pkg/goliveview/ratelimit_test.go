@@ -0,0 +1,257 @@
+package goliveview
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRateLimitsBucket(t *testing.T) {
+	var limits rateLimits
+	if _, _, ok := limits.bucket("todos/new"); ok {
+		t.Fatal("no limits set, want no bucket")
+	}
+
+	all := RateLimit{Interval: time.Second, Burst: 10}
+	create := RateLimit{Interval: time.Minute, Burst: 1}
+	limits.set(all, nil)
+	limits.set(create, []string{"todos/new"})
+
+	tests := []struct {
+		id    string
+		limit RateLimit
+		key   string
+	}{
+		{"todos/new", create, "todos/new"},
+		{"todos/delete", all, "*"},
+		{"todos/edit", all, "*"},
+	}
+	for _, test := range tests {
+		limit, key, ok := limits.bucket(test.id)
+		if !ok || limit != test.limit || key != test.key {
+			t.Errorf("%s: got %+v in %q (%v), want %+v in %q", test.id, limit, key, ok, test.limit, test.key)
+		}
+	}
+}
+
+func TestLimitersAllow(t *testing.T) {
+	var limits rateLimits
+	limits.set(RateLimit{Interval: time.Hour, Burst: 2}, nil)
+	limits.set(RateLimit{Interval: time.Hour, Burst: 1}, []string{"todos/new"})
+
+	l := newLimiters()
+	if !l.allow(limits, "todos/new") {
+		t.Fatal("first change request rejected")
+	}
+	if l.allow(limits, "todos/new") {
+		t.Error("change request over its own burst allowed")
+	}
+	// the change requests without their own limit share the "*" bucket, todos/new didn't use it
+	for i, id := range []string{"todos/delete", "todos/edit"} {
+		if !l.allow(limits, id) {
+			t.Errorf("change request %d (%s) within the global burst rejected", i, id)
+		}
+	}
+	if l.allow(limits, "todos/delete") {
+		t.Error("change request over the global burst allowed")
+	}
+
+	if !newLimiters().allow(rateLimits{}, "todos/new") {
+		t.Error("change request without limits rejected")
+	}
+}
+
+func TestUserLimiters(t *testing.T) {
+	u := &userLimiters{users: make(map[string]*userLimiter)}
+	first, err := u.connect("alice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("alice", 2); err != ErrTooManyConnections {
+		t.Fatalf("third connection: got %v, want %v", err, ErrTooManyConnections)
+	}
+	if _, err := u.connect("bob", 2); err != nil {
+		t.Fatalf("another user's connection: %v", err)
+	}
+	// posted forms share the user's buckets without counting as connections
+	if l := u.post("alice"); l != first {
+		t.Error("a posted form got other buckets than the user's connections")
+	}
+	u.postDone("alice")
+	u.disconnect("alice")
+	if l, err := u.connect("alice", 2); err != nil || l != first {
+		t.Fatalf("reconnecting after a disconnect: %v", err)
+	}
+	if _, err := u.connect("alice", 0); err != nil {
+		t.Fatalf("no maximum: %v", err)
+	}
+}
+
+func TestUserLimitersPrune(t *testing.T) {
+	u := &userLimiters{users: make(map[string]*userLimiter)}
+	if _, err := u.connect("connected", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("recent", 1); err != nil {
+		t.Fatal(err)
+	}
+	u.disconnect("recent")
+	if _, err := u.connect("gone", 1); err != nil {
+		t.Fatal(err)
+	}
+	u.disconnect("gone")
+	u.post("posting")
+	u.post("posted")
+	u.postDone("posted")
+
+	past := time.Now().Add(-2 * userLimiterTTL)
+	for _, user := range []string{"connected", "gone", "posting", "posted"} {
+		u.users[user].lastSeen = past
+	}
+	u.prune()
+
+	for user, want := range map[string]bool{
+		"connected": true,
+		"recent":    true,
+		"gone":      false,
+		"posting":   true,
+		"posted":    false,
+	} {
+		if _, ok := u.users[user]; ok != want {
+			t.Errorf("%s: kept %v, want %v", user, ok, want)
+		}
+	}
+}
+
+// newTestView serves a view with the "ping" change request, its templates are written to a temporary directory.
+func newTestView(t *testing.T, options ...ControllerOption) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"layout.html":   `<main>{{template "glv-error" .}}{{template "content" .}}</main>`,
+		"page.html":     `{{define "content"}}<p>page</p>{{end}}`,
+		"partials.html": `{{define "glv-error"}}<div id="glv-error">{{with .error}}{{.}}{{end}}</div>{{end}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := "test"
+	options = append([]ControllerOption{WithMetricsRegisterer(prometheus.NewRegistry())}, options...)
+	wc := WebsocketController(&name, options...)
+	view := wc.NewView(filepath.Join(dir, "page.html"),
+		WithLayout(filepath.Join(dir, "layout.html")),
+		WithPartials(filepath.Join(dir, "partials.html")),
+		WithChangeRequestHandlers(map[string]ChangeRequestHandler{
+			"ping": func(ctx context.Context, r ChangeRequest, s Session) error {
+				return nil
+			},
+		}))
+	server := httptest.NewServer(view)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestDialer returns a dialer with the cookie of a single user of the view.
+func newTestDialer(t *testing.T, server *httptest.Server) *websocket.Dialer {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Jar: jar}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return &websocket.Dialer{Jar: jar}
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestChangeRequestRateLimited(t *testing.T) {
+	server := newTestView(t, WithConnectionRateLimit(RateLimit{Interval: time.Hour, Burst: 1}))
+	ws, _, err := newTestDialer(t, server).Dial(wsURL(server), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"id":"ping"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the allowed change request hides the error, the rejected one shows it
+	want := []string{"", ErrRateLimited.Error()}
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i, wantErr := range want {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Actions) != 1 || m.Actions[0].Action != Replace || m.Actions[0].Target != "glv-error" {
+			t.Fatalf("message %d: got %s, want a replace of glv-error", i, data)
+		}
+		if got := m.Actions[0].HTML; got != `<div id="glv-error">`+wantErr+`</div>` {
+			t.Errorf("message %d: got %s, want the error %q", i, got, wantErr)
+		}
+	}
+}
+
+func TestTooManyConnections(t *testing.T) {
+	server := newTestView(t, WithMaxConnectionsPerUser(1))
+	dialer := newTestDialer(t, server)
+
+	first, _, err := dialer.Dial(wsURL(server), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := dialer.Dial(wsURL(server), nil)
+	if err == nil {
+		t.Fatal("second connection of the same user accepted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want status %d", resp, http.StatusTooManyRequests)
+	}
+	// another user isn't counted against the first one's connections
+	other, _, err := newTestDialer(t, server).Dial(wsURL(server), nil)
+	if err != nil {
+		t.Fatalf("another user's connection: %v", err)
+	}
+	other.Close()
+
+	// the slot is freed once the first connection is closed
+	first.Close()
+	var second *websocket.Conn
+	for i := 0; i < 50 && second == nil; i++ {
+		second, _, err = dialer.Dial(wsURL(server), nil)
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if second == nil {
+		t.Fatalf("reconnecting after closing the first connection: %v", err)
+	}
+	second.Close()
+}
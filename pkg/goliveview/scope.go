@@ -0,0 +1,72 @@
+package goliveview
+
+import (
+	"sync"
+)
+
+// Scope selects the connections a Session sends its changes to.
+type Scope struct {
+	kind  scopeKind
+	topic string
+}
+
+type scopeKind int
+
+const (
+	connectionScope scopeKind = iota
+	userScope
+	viewScope
+	topicScope
+)
+
+var (
+	// ScopeConnection sends to the connection of the change request only. Sessions send with it by default.
+	ScopeConnection = Scope{kind: connectionScope}
+	// ScopeUser sends to every connection of the user to the same view, e.g. all of their tabs.
+	ScopeUser = Scope{kind: userScope}
+	// ScopeView sends to everyone connected to the same view.
+	ScopeView = Scope{kind: viewScope}
+)
+
+// ScopeTopic sends to the connections subscribed to topic with WithSubscribeTopic.
+func ScopeTopic(topic string) Scope {
+	return Scope{kind: topicScope, topic: topic}
+}
+
+func (s Scope) String() string {
+	switch s.kind {
+	case userScope:
+		return "user"
+	case viewScope:
+		return "view"
+	case topicScope:
+		return "topic:" + s.topic
+	default:
+		return "connection"
+	}
+}
+
+// viewKeyPrefix starts the keys of the view scopes, every connection is in the scope of its view.
+const viewKeyPrefix = "view:"
+
+// key of the connections of the scope in the controller, view is the url path of the connection.
+func (s Scope) key(view, user string) string {
+	switch s.kind {
+	case userScope:
+		return "user:" + view + ":" + user
+	case viewScope:
+		return viewKeyPrefix + view
+	case topicScope:
+		return "topic:" + s.topic
+	default:
+		return ""
+	}
+}
+
+// conn serializes the writes to a connection, changes are sent to it from the handlers of other connections too.
+type conn struct {
+	id        string
+	transport transport
+	mu        sync.Mutex
+	seq       uint64
+}
@@ -4,17 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/lithammer/shortuuid/v3"
 
 	"github.com/yosssi/gohtml"
-
-	"github.com/gorilla/websocket"
 )
 
 type ActionType string
@@ -48,6 +45,8 @@ type ChangeRequest struct {
 	Target   string          `json:"target,omitempty"`
 	Targets  string          `json:"targets,omitempty"`
 	Template string          `json:"template"`
+	// timer is set for the change requests of SendAfter and Every, they aren't rate limited.
+	timer bool
 }
 
 func (c ChangeRequest) DecodeParams(v interface{}) error {
@@ -95,21 +94,72 @@ type Session interface {
 	Change(changeset M)
 	Flash(duration time.Duration, changeset M)
 	Temporary(keys ...string)
+	// Redirect navigates the client to url once it has applied the changes sent with it.
+	Redirect(url string)
+	// PushEvent dispatches a CustomEvent named name with payload as its detail on the client's window,
+	// e.g. for a stimulus action like todos:created@window->reset-form#reset.
+	PushEvent(name string, payload interface{})
+	// PushEventTo dispatches the event on the element with the id target, it bubbles up to the window.
+	PushEventTo(target, name string, payload interface{})
+	// Exec runs commands on the client after it has applied the changes sent with them, e.g. Focus("text").
+	Exec(commands ...Command)
+	// SendAfter runs the change request changeRequestID with params on the session's connection after d, with
+	// its handler like one sent by the client but without the client's action, target and template. It replaces
+	// the timer of changeRequestID and is cancelled when the connection closes.
+	SendAfter(d time.Duration, changeRequestID string, params interface{})
+	// Every runs the change request every d until the connection closes, see SendAfter.
+	Every(d time.Duration, changeRequestID string, params interface{})
+	// CancelTimer cancels the timer of changeRequestID of SendAfter or Every.
+	CancelTimer(changeRequestID string)
+	// Scope returns the session sending its changes to the connections of scope.
+	// A change request's session sends to its own connection only.
+	Scope(scope Scope) Session
 	SessionStore
 }
 
 type session struct {
 	rootTemplate         *template.Template
-	topic                string
 	changeRequest        ChangeRequest
-	conns                map[string]*websocket.Conn
-	messageType          int
+	conn                 *conn
+	batch                *batch
+	scope                Scope
+	scopeConnections     func(scope Scope) []*conn
+	timers               *timers
+	runTimer             func(changeRequest ChangeRequest)
 	store                SessionStore
 	temporaryKeys        []string
 	enableHTMLFormatting bool
+	controllerName       string
+	metrics              *metrics
+	logger               *slog.Logger
+}
+
+func (s session) Scope(scope Scope) Session {
+	s.scope = scope
+	return s
+}
+
+// recipients are the connections of the session's scope.
+func (s session) recipients() []*conn {
+	if s.scope == ScopeConnection {
+		return []*conn{s.conn}
+	}
+	conns := s.scopeConnections(s.scope)
+	if s.scope.kind == topicScope {
+		return conns
+	}
+	// a posted form's connection isn't registered but is in the scope of its user and view
+	for _, c := range conns {
+		if c == s.conn {
+			return conns
+		}
+	}
+	return append(conns, s.conn)
 }
 
+// setError shows the error to the connection of the change request only.
 func (s session) setError(userMessage string, errs ...error) {
+	s.scope = ScopeConnection
 	if len(errs) != 0 {
 		var errstrs []string
 		for _, err := range errs {
@@ -118,7 +168,7 @@ func (s session) setError(userMessage string, errs ...error) {
 			}
 			errstrs = append(errstrs, err.Error())
 		}
-		log.Printf("err: %v, errors: %v\n", userMessage, strings.Join(errstrs, ","))
+		s.logger.Warn(userMessage, "errors", strings.Join(errstrs, ","))
 	}
 
 	s.write(Replace, "glv-error", "", "glv-error",
@@ -128,47 +178,95 @@ func (s session) setError(userMessage string, errs ...error) {
 }
 
 func (s session) unsetError() {
+	s.scope = ScopeConnection
 	s.write(Replace, "glv-error", "", "glv-error", nil)
 }
 
 func (s session) write(action ActionType, target, targets, template string, data M) {
 	if action == "" {
-		log.Printf("err action is empty\n")
+		s.logger.Error("action is empty")
 		return
 	}
 	// stream response
 	if target == "" && targets == "" {
-		log.Printf("err target/targets %s/%s empty for changeRequest %+v\n", target, targets, s.changeRequest)
+		s.logger.Error("target/targets empty", "action", action, "template", template)
 		return
 	}
 	var buf bytes.Buffer
 	if template != "" && action != Remove {
+		renderStart := time.Now()
 		err := s.rootTemplate.ExecuteTemplate(&buf, template, data)
+		s.metrics.renderDurations.WithLabelValues(s.controllerName, template).Observe(time.Since(renderStart).Seconds())
 		if err != nil {
-			log.Printf("err %v,while executing template for changeRequest %+v\n", err, s.changeRequest)
+			s.logger.Error("executing template", "template", template, "err", err)
 			return
 		}
 	}
 	html := buf.String()
-	var message string
+	if s.enableHTMLFormatting {
+		html = gohtml.Format(html)
+	}
+
+	a := messageAction{Action: action, Target: target, Targets: targets, HTML: html}
 	if targets != "" {
-		message = fmt.Sprintf(turboTargetsWrapper, action, targets, html)
-	} else {
-		message = fmt.Sprintf(turboTargetWrapper, action, target, html)
+		a.Target = ""
 	}
+	s.add(func(m *message) {
+		m.Actions = append(m.Actions, a)
+	})
+}
 
-	if s.enableHTMLFormatting {
-		message = gohtml.Format(message)
+// add adds to the messages of the recipients, they are sent right away outside a change request.
+func (s session) add(f func(m *message)) {
+	if s.batch != nil {
+		s.batch.add(s.recipients(), f)
+		return
+	}
+	var m message
+	f(&m)
+	for _, c := range s.recipients() {
+		s.send(c, m)
 	}
+}
 
-	for topic, conn := range s.conns {
-		err := conn.WriteMessage(s.messageType, []byte(message))
-		if err != nil {
-			log.Printf("err writing message for topic:%v, %v, closing conn", topic, err)
-			conn.Close()
-			return
+// flush sends the messages batched by the change request.
+func (s session) flush() {
+	if s.batch == nil {
+		return
+	}
+	conns, messages := s.batch.take()
+	for _, c := range conns {
+		if m := messages[c]; !m.empty() {
+			s.send(c, *m)
+		}
+	}
+}
+
+// respond sends the messages batched for the other connections and returns the one of the session's connection.
+func (s session) respond() message {
+	conns, messages := s.batch.take()
+	var own message
+	for _, c := range conns {
+		m := messages[c]
+		if c == s.conn {
+			own = *m
+			continue
 		}
+		if !m.empty() {
+			s.send(c, *m)
+		}
+	}
+	return own
+}
+
+func (s session) send(c *conn, m message) {
+	n, err := c.send(m)
+	if err != nil {
+		s.logger.Warn("writing message, closing conn", "to_conn_id", c.id, "scope", s.scope, "err", err)
+		c.transport.close()
+		return
 	}
+	s.metrics.sentBytes.WithLabelValues(s.controllerName).Add(float64(n))
 }
 
 func (s session) Temporary(keys ...string) {
@@ -240,7 +338,7 @@ func (s session) change(changeset M) {
 	// update store
 	err := s.store.Set(changeset)
 	if err != nil {
-		log.Printf("error store.set %v\n", err)
+		s.logger.Error("store.set", "err", err)
 	}
 }
 
@@ -260,18 +358,94 @@ func (s session) Flash(duration time.Duration, changeset M) {
 	changeset["flash_id"] = flashID
 
 	s.change(changeset)
-	go func(target string, changeset M) {
-		time.Sleep(duration)
+	// a posted form's page is rendered without the flash's removal
+	if s.timers == nil {
+		return
+	}
+	// the handler has returned by the time the flash is removed
+	s.batch = nil
+	s.timers.schedule(flashTimerPrefix+flashID, duration, false, func() {
 		nilDataChangeSet["action"] = Remove
 		nilDataChangeSet["target"] = flashID
 		s.change(nilDataChangeSet)
-	}(flashID, nilDataChangeSet)
+	})
 }
 
 func (s session) Change(changeset M) {
 	s.change(changeset)
 }
 
+func (s session) Redirect(url string) {
+	s.add(func(m *message) {
+		m.Redirect = url
+	})
+}
+
+func (s session) PushEvent(name string, payload interface{}) {
+	s.PushEventTo("", name, payload)
+}
+
+func (s session) PushEventTo(target, name string, payload interface{}) {
+	if name == "" {
+		s.logger.Error("event name is empty", "target", target)
+		return
+	}
+	e := event{Name: name, Target: target, Detail: payload}
+	s.add(func(m *message) {
+		m.Events = append(m.Events, e)
+	})
+}
+
+func (s session) Exec(commands ...Command) {
+	if len(commands) == 0 {
+		return
+	}
+	s.add(func(m *message) {
+		m.Commands = append(m.Commands, commands...)
+	})
+}
+
+// flashTimerPrefix starts the ids of the timers removing flashes, they aren't change request ids.
+const flashTimerPrefix = "_glv_flash_"
+
+func (s session) SendAfter(d time.Duration, changeRequestID string, params interface{}) {
+	s.schedule(d, false, changeRequestID, params)
+}
+
+func (s session) Every(d time.Duration, changeRequestID string, params interface{}) {
+	if d <= 0 {
+		s.logger.Error("timer interval must be positive", "timer_change_request_id", changeRequestID, "interval", d)
+		return
+	}
+	s.schedule(d, true, changeRequestID, params)
+}
+
+func (s session) schedule(d time.Duration, repeat bool, changeRequestID string, params interface{}) {
+	// a posted form has no connection left to run it on
+	if s.timers == nil {
+		s.logger.Debug("ignored timer of posted form", "timer_change_request_id", changeRequestID)
+		return
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		s.logger.Error("marshal timer params", "timer_change_request_id", changeRequestID, "err", err)
+		return
+	}
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	changeRequest := ChangeRequest{ID: changeRequestID, Params: data, timer: true}
+	s.timers.schedule(changeRequestID, d, repeat, func() {
+		s.runTimer(changeRequest)
+	})
+}
+
+func (s session) CancelTimer(changeRequestID string) {
+	if s.timers != nil {
+		s.timers.cancel(changeRequestID)
+	}
+}
+
 func (s session) Set(m M) error {
 	return s.store.Set(m)
 }
@@ -281,21 +455,3 @@ func (s session) Get(key string) (interface{}, bool) {
 }
 
 type ChangeRequestHandler func(ctx context.Context, req ChangeRequest, session Session) error
-
-var turboTargetWrapper = `{
-							"message":
-							  "<turbo-stream action="%s" target="%s">
-								<template>
-									%s
-								</template>
-							   </turbo-stream>"
-						  }`
-
-var turboTargetsWrapper = `{
-							"message":
-							  "<turbo-stream action="%s" targets="%s">
-								<template>
-									%s
-								</template>
-							   </turbo-stream>"
-						  }`
@@ -0,0 +1,71 @@
+package goliveview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrShuttingDown rejects websocket upgrades once the controller has started draining.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// reconnectMessage is sent in the close frame to tell clients to reconnect, possibly to another instance.
+var reconnectMessage = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "reconnect")
+
+// Shutdown stops accepting websocket upgrades, asks the connected clients to reconnect elsewhere and
+// waits for the in-flight change requests to finish. Connections still open when ctx is done are closed.
+func (wc *websocketController) Shutdown(ctx context.Context) error {
+	// no request is counted active once draining, the wait group isn't added to while waited for
+	wc.drainMu.Lock()
+	wc.draining.Store(true)
+	wc.drainMu.Unlock()
+
+	wc.RLock()
+	var conns []transport
+	for key, connMap := range wc.scopeConns {
+		if !strings.HasPrefix(key, viewKeyPrefix) {
+			continue
+		}
+		for _, c := range connMap {
+			conns = append(conns, c.transport)
+		}
+	}
+	wc.RUnlock()
+
+	deadline := time.Now().Add(time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	for _, conn := range conns {
+		if err := conn.restart(deadline); err != nil {
+			wc.logger.Debug("write close message", "err", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wc.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		wc.logger.Info("drained connections", "connections", len(conns))
+		return nil
+	case <-ctx.Done():
+		for _, conn := range conns {
+			conn.close()
+		}
+		wc.logger.Warn("shutdown timed out, closed connections", "connections", len(conns))
+		return ctx.Err()
+	}
+}
+
+func rejectDraining(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+}
@@ -7,7 +7,7 @@ type store struct {
 	sync.RWMutex
 }
 
-func (s store) Set(m M) error {
+func (s *store) Set(m M) error {
 	s.Lock()
 	defer s.Unlock()
 	for k, v := range m {
@@ -16,7 +16,7 @@ func (s store) Set(m M) error {
 	return nil
 }
 
-func (s store) Get(key string) (interface{}, bool) {
+func (s *store) Get(key string) (interface{}, bool) {
 	s.RLock()
 	defer s.RUnlock()
 	v, ok := s.data[key]
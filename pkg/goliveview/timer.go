@@ -0,0 +1,103 @@
+package goliveview
+
+import (
+	"sync"
+	"time"
+)
+
+// timers schedules the change requests of Session.SendAfter and Session.Every on a connection. They fire
+// into the connection's loop, which runs them one at a time with the ones sent by the client, and stop when
+// the connection closes.
+type timers struct {
+	fired chan func()
+	done  chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	byID    map[string]chan struct{}
+}
+
+func newTimers() *timers {
+	return &timers{
+		fired: make(chan func()),
+		done:  make(chan struct{}),
+		byID:  make(map[string]chan struct{}),
+	}
+}
+
+// schedule runs f in the connection's loop after d, every d if repeat. It replaces the timer of id.
+func (t *timers) schedule(id string, d time.Duration, repeat bool, f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	if cancel, ok := t.byID[id]; ok {
+		close(cancel)
+	}
+	cancel := make(chan struct{})
+	t.byID[id] = cancel
+	run := f
+	if !repeat {
+		run = func() {
+			// f may schedule id again
+			t.remove(id, cancel)
+			f()
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-cancel:
+				return
+			case <-t.done:
+				return
+			}
+			// a timer cancelled while the loop is busy doesn't fire
+			select {
+			case t.fired <- run:
+			case <-cancel:
+				return
+			case <-t.done:
+				return
+			}
+			if !repeat {
+				return
+			}
+		}
+	}()
+}
+
+// remove forgets the timer of id if it's still the one of cancel.
+func (t *timers) remove(id string, cancel chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byID[id] == cancel {
+		delete(t.byID, id)
+	}
+}
+
+// cancel stops the timer of id.
+func (t *timers) cancel(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.byID[id]; ok {
+		close(cancel)
+		delete(t.byID, id)
+	}
+}
+
+// stop stops all the timers, the ones scheduled afterwards never fire.
+func (t *timers) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	close(t.done)
+	t.byID = make(map[string]chan struct{})
+}
@@ -0,0 +1,198 @@
+package goliveview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnectionHeader carries the id of the server-sent events connection a change request is posted for.
+const ConnectionHeader = "X-Glv-Connection"
+
+// eventsKeepAlive keeps proxies from closing an idle event stream.
+const eventsKeepAlive = 15 * time.Second
+
+// sseRequests is how many posted change requests of a connection may wait for its handler.
+const sseRequests = 16
+
+var errConnectionClosed = errors.New("connection closed")
+
+// transport writes the messages of a connection to its client.
+type transport interface {
+	write(data []byte) error
+	// restart asks the client to reconnect, possibly to another instance, and closes the connection.
+	restart(deadline time.Time) error
+	close() error
+}
+
+type websocketTransport struct {
+	ws *websocket.Conn
+}
+
+func (t websocketTransport) write(data []byte) error {
+	return t.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+func (t websocketTransport) restart(deadline time.Time) error {
+	return t.ws.WriteControl(websocket.CloseMessage, reconnectMessage, deadline)
+}
+
+func (t websocketTransport) close() error {
+	return t.ws.Close()
+}
+
+// sseTransport streams the messages of a connection as server-sent events for clients which can't open a
+// websocket, e.g. behind a proxy stripping the Upgrade header. The client posts its change requests instead.
+type sseTransport struct {
+	user     string
+	view     string
+	requests chan []byte
+	done     chan struct{}
+
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	closed bool
+}
+
+func newSSETransport(w http.ResponseWriter, user, view string) *sseTransport {
+	return &sseTransport{
+		user:     user,
+		view:     view,
+		requests: make(chan []byte, sseRequests),
+		done:     make(chan struct{}),
+		w:        w,
+		rc:       http.NewResponseController(w),
+	}
+}
+
+// open starts the event stream with the id of the connection, the client sends it along with its change requests.
+func (t *sseTransport) open(connID string) error {
+	h := t.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// nginx buffers responses by default
+	h.Set("X-Accel-Buffering", "no")
+	t.w.WriteHeader(http.StatusOK)
+	return t.event("glv-connect", connID)
+}
+
+// event writes an event, events without a name are messages.
+func (t *sseTransport) event(name, data string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return errConnectionClosed
+	}
+	var b strings.Builder
+	if name != "" {
+		fmt.Fprintf(&b, "event: %s\n", name)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return t.flush(b.String())
+}
+
+func (t *sseTransport) flush(s string) error {
+	if _, err := io.WriteString(t.w, s); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+func (t *sseTransport) write(data []byte) error {
+	return t.event("", string(data))
+}
+
+// keepAlive comments the stream until the connection is closed.
+func (t *sseTransport) keepAlive() {
+	ticker := time.NewTicker(eventsKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			if !t.closed {
+				t.flush(": keep-alive\n\n")
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// restart ends the stream, the client's EventSource reconnects after the retry delay, spread out so that
+// all clients don't hit the next instance at once.
+func (t *sseTransport) restart(deadline time.Time) error {
+	t.mu.Lock()
+	var err error
+	if !t.closed {
+		t.rc.SetWriteDeadline(deadline)
+		err = t.flush(fmt.Sprintf("retry: %d\n\n", 250+rand.Intn(1000)))
+	}
+	t.mu.Unlock()
+	t.close()
+	return err
+}
+
+// close stops the handler of the connection, nothing is written to the stream afterwards.
+func (t *sseTransport) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+	return nil
+}
+
+// receive returns the next posted change request.
+func (t *sseTransport) receive(ctx context.Context) ([]byte, error) {
+	select {
+	case message := <-t.requests:
+		return message, nil
+	case <-t.done:
+		return nil, errConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// post queues a change request for the handler of the connection.
+func (t *sseTransport) post(message []byte) bool {
+	select {
+	case t.requests <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameOrigin is the origin check of websocket upgrades without WithAllowedOrigins.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
@@ -1,7 +1,9 @@
 package goliveview
 
 import (
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"net/http"
 	"os"
@@ -29,6 +31,7 @@ type viewOpt struct {
 	funcMap               template.FuncMap
 	onMountFunc           OnMount
 	changeRequestHandlers map[string]ChangeRequestHandler
+	allowedChanges        map[string]Changes
 }
 
 func WithLayout(layout string) ViewOption {
@@ -79,6 +82,16 @@ func WithChangeRequestHandlers(changeRequestHandlers map[string]ChangeRequestHan
 	}
 }
 
+// WithAllowedChanges declares by change request id the action, target and template clients may send.
+// A change request sending anything else is rejected with ErrChangeNotAllowed, the client values of an
+// undeclared change request are ignored. Without it the client values of every change request are ignored,
+// its handler sets the action, target and template of its changes itself.
+func WithAllowedChanges(changes map[string]Changes) ViewOption {
+	return func(o *viewOpt) {
+		o.allowedChanges = changes
+	}
+}
+
 func find(p string, extensions []string) []string {
 	var files []string
 
@@ -110,3 +123,10 @@ func find(p string, extensions []string) []string {
 
 	return files
 }
+
+// writeStatusPage is the page of a view which can't be mounted.
+func writeStatusPage(w io.Writer, status int) {
+	// TODO: custom error page
+	fmt.Fprintf(w, `<div style="text-align:center"><h1>%d</h1></div>
+<div style="text-align:center"><a href="javascript:history.back()">back</a></div>`, status)
+}
@@ -0,0 +1,268 @@
+package turbo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Masterminds/sprig"
+	rl "github.com/adnaan/renderlayout"
+)
+
+// FrameHeader names the turbo frame a request was made from.
+const FrameHeader = "Turbo-Frame"
+
+// FieldErrorsKey is the template variable containing the FieldErrors of a failed form submission.
+const FieldErrorsKey = "field_errors"
+
+// FieldErrors maps the fields of a form to what's wrong with them. A data func wraps them so that
+// they're shown to the user, e.g. fmt.Errorf("%w", turbo.FieldErrors{"text": "a todo can't be empty"}).
+type FieldErrors map[string]string
+
+func (f FieldErrors) Error() string {
+	var s []string
+	for field, err := range f {
+		s = append(s, fmt.Sprintf("%s: %s", field, err))
+	}
+	sort.Strings(s)
+	return strings.Join(s, ", ")
+}
+
+// FrameID returns the id of the turbo frame a request was made from, it's empty for a page visit.
+func FrameID(r *http.Request) string {
+	return r.Header.Get(FrameHeader)
+}
+
+type Option func(*renderer)
+
+// TemplatesPath is the root of the views, the same as renderlayout's. Default value is "templates".
+func TemplatesPath(templatesPath string) Option {
+	return func(r *renderer) {
+		r.root = templatesPath
+	}
+}
+
+// PartialsPath is the dir of the partials within the templates path. Default value is "partials".
+func PartialsPath(partials string) Option {
+	return func(r *renderer) {
+		r.partials = partials
+	}
+}
+
+// Extension of the view files. Default value is ".html".
+func Extension(extension string) Option {
+	return func(r *renderer) {
+		r.extension = extension
+	}
+}
+
+// ErrorKey is the template variable containing view errors, the same as renderlayout's. Default value is "errors".
+func ErrorKey(key string) Option {
+	return func(r *renderer) {
+		r.errorKey = key
+	}
+}
+
+// Logger sets the renderer's logger. Every line carries the view, method and path of its request.
+// Defaults to a text logger on stderr at warn level.
+func Logger(logger *slog.Logger) Option {
+	return func(r *renderer) {
+		r.logger = logger
+	}
+}
+
+// DisableCache parses the views on every request.
+func DisableCache(disableCache bool) Option {
+	return func(r *renderer) {
+		r.disableCache = disableCache
+	}
+}
+
+type renderer struct {
+	render       rl.Render
+	root         string
+	partials     string
+	extension    string
+	errorKey     string
+	disableCache bool
+	logger       *slog.Logger
+
+	mu        sync.Mutex
+	templates map[string]*template.Template
+}
+
+// New wraps a renderlayout Render for turbo. Its handlers:
+//
+//   - answer a request from a turbo frame with only the view's template named like the frame, e.g.
+//     {{define "todos"}}<turbo-frame id="todos">...</turbo-frame>{{end}}, views without it are rendered whole.
+//   - respond with the turbo stream actions returned by the data funcs when the request accepts them.
+//   - respond to a failed form submission with 422 Unprocessable Entity, turbo renders it instead of
+//     ignoring it.
+//
+// Frame and stream templates are parsed from the view and the partials, they don't get renderlayout's default data.
+func New(render rl.Render, opts ...Option) (rl.Render, error) {
+	tr := &renderer{
+		render:    render,
+		root:      "templates",
+		partials:  "partials",
+		extension: ".html",
+		errorKey:  "errors",
+		logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		})),
+		templates: make(map[string]*template.Template),
+	}
+	for _, opt := range opts {
+		opt(tr)
+	}
+	if _, err := os.Stat(filepath.Join(tr.root, tr.partials)); err != nil {
+		return nil, err
+	}
+	return tr.handler, nil
+}
+
+func (tr *renderer) handler(view string, dataFuncs ...rl.Data) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", FrameHeader)
+		logger := tr.logger.With("view", view, "method", r.Method, "path", r.URL.Path)
+		rw := &responseWriter{ResponseWriter: w}
+		viewData := make(rl.D)
+		var errStrings []string
+		var actions []StreamAction
+		for _, dataFunc := range dataFuncs {
+			data, err := dataFunc(rw, r)
+			if err != nil {
+				// a wrapped error is shown to the user, the same as renderlayout.
+				if viewError := errors.Unwrap(err); viewError != nil {
+					errStrings = append(errStrings, first(strings.ToLower(viewError.Error())))
+					logger.Warn("user error", "err", err)
+				} else {
+					logger.Error("running data func", "err", err)
+				}
+				var fieldErrors FieldErrors
+				if errors.As(err, &fieldErrors) {
+					viewData[FieldErrorsKey] = fieldErrors
+				}
+			}
+			for k, v := range data {
+				if k == StreamKey {
+					a, _ := v.([]StreamAction)
+					actions = append(actions, a...)
+					continue
+				}
+				viewData[k] = v
+			}
+			// e.g. a redirect
+			if rw.written {
+				return
+			}
+		}
+		if len(errStrings) > 0 {
+			viewData[tr.errorKey] = errStrings
+		}
+
+		status := http.StatusOK
+		if len(errStrings) > 0 && r.Method != http.MethodGet {
+			status = http.StatusUnprocessableEntity
+		}
+
+		if len(actions) > 0 && len(errStrings) == 0 && AcceptsStream(r) {
+			t, err := tr.template(view)
+			if err == nil {
+				err = writeStream(w, t, actions, viewData)
+			}
+			if err != nil {
+				logger.Error("rendering stream", "err", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if frame := FrameID(r); frame != "" {
+			t, err := tr.template(view)
+			if err != nil {
+				logger.Error("parsing frame", "err", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if t.Lookup(frame) != nil {
+				var b bytes.Buffer
+				if err := t.ExecuteTemplate(&b, frame, viewData); err != nil {
+					logger.Error("rendering frame", "frame", frame, "err", err)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(status)
+				w.Write(b.Bytes())
+				return
+			}
+		}
+
+		tr.render(view, rl.StaticData(viewData))(&statusWriter{ResponseWriter: w, status: status}, r)
+	}
+}
+
+// template returns the parsed view and partials.
+func (tr *renderer) template(view string) (*template.Template, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if t, ok := tr.templates[view]; ok && !tr.disableCache {
+		return t, nil
+	}
+	partials, err := filepath.Glob(filepath.Join(tr.root, tr.partials, "*"+tr.extension))
+	if err != nil {
+		return nil, err
+	}
+	files := append(partials, filepath.Join(tr.root, view+tr.extension))
+	t, err := template.New(view).Funcs(sprig.FuncMap()).ParseFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	tr.templates[view] = t
+	return t, nil
+}
+
+func first(str string) string {
+	if len(str) == 0 {
+		return ""
+	}
+	tmp := []rune(str)
+	tmp[0] = unicode.ToUpper(tmp[0])
+	return string(tmp)
+}
+
+// responseWriter notes whether a data func has written the response.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// statusWriter replaces the status renderlayout always responds with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(int) {
+	w.ResponseWriter.WriteHeader(w.status)
+}
@@ -0,0 +1,81 @@
+package turbo
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"strings"
+
+	rl "github.com/adnaan/renderlayout"
+)
+
+// StreamContentType is the content type of turbo stream responses.
+const StreamContentType = "text/vnd.turbo-stream.html"
+
+// StreamKey is the data key of the turbo stream actions returned by a data func.
+const StreamKey = "turbo_stream"
+
+// StreamAction is rendered as a <turbo-stream> element.
+type StreamAction struct {
+	Action string
+	Target string
+	// Template of the view rendered within the action, none for remove.
+	Template string
+	// Data of the template, the view's data when nil.
+	Data interface{}
+}
+
+// Stream returns the data of a data func responding with turbo stream actions to requests accepting them,
+// other requests render the view.
+func Stream(actions ...StreamAction) rl.D {
+	return rl.D{StreamKey: actions}
+}
+
+// Append, Prepend, Replace and Update render a template of the view into their target, Remove removes it.
+func Append(target, template string, data interface{}) StreamAction {
+	return StreamAction{Action: "append", Target: target, Template: template, Data: data}
+}
+
+func Prepend(target, template string, data interface{}) StreamAction {
+	return StreamAction{Action: "prepend", Target: target, Template: template, Data: data}
+}
+
+func Replace(target, template string, data interface{}) StreamAction {
+	return StreamAction{Action: "replace", Target: target, Template: template, Data: data}
+}
+
+func Update(target, template string, data interface{}) StreamAction {
+	return StreamAction{Action: "update", Target: target, Template: template, Data: data}
+}
+
+func Remove(target string) StreamAction {
+	return StreamAction{Action: "remove", Target: target}
+}
+
+// AcceptsStream reports whether a request accepts turbo stream responses, turbo asks for them when submitting forms.
+func AcceptsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), StreamContentType)
+}
+
+func writeStream(w http.ResponseWriter, t *template.Template, actions []StreamAction, viewData rl.D) error {
+	var b bytes.Buffer
+	for _, a := range actions {
+		fmt.Fprintf(&b, "<turbo-stream action=\"%s\" target=\"%s\"><template>",
+			html.EscapeString(a.Action), html.EscapeString(a.Target))
+		if a.Template != "" {
+			var data interface{} = viewData
+			if a.Data != nil {
+				data = a.Data
+			}
+			if err := t.ExecuteTemplate(&b, a.Template, data); err != nil {
+				return err
+			}
+		}
+		b.WriteString("</template></turbo-stream>\n")
+	}
+	w.Header().Set("Content-Type", StreamContentType)
+	_, err := w.Write(b.Bytes())
+	return err
+}
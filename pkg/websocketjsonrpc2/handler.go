@@ -2,10 +2,20 @@ package websocketjsonrpc2
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/lithammer/shortuuid/v3"
 
@@ -21,6 +31,16 @@ type opt struct {
 	upgrader           websocket.Upgrader
 	resultHook         func(method string, result interface{}) interface{}
 	onConnectMethod    string
+	authorizer         Authorizer
+	allowedOrigins     []string
+	connRateLimits     rateLimits
+	userRateLimits     rateLimits
+	readLimit          int64
+	maxUserConnections int
+	metricsRegisterer  prometheus.Registerer
+	tracerProvider     trace.TracerProvider
+	metrics            *metrics
+	logger             *slog.Logger
 }
 
 type Option func(*opt)
@@ -55,52 +75,224 @@ func WithOnConnectMethod(method string) Option {
 	}
 }
 
+// WithAuthorizer runs the authorizer before a websocket is upgraded or a http request is served.
+// The returned principal is available to every Method through PrincipalFromContext.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(o *opt) {
+		o.authorizer = authorizer
+	}
+}
+
+// WithAllowedOrigins restricts requests to the given origins. "*" allows any origin.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *opt) {
+		o.allowedOrigins = origins
+	}
+}
+
+// WithConnectionRateLimit limits the calls of a single websocket connection.
+// The limit applies to the given methods or, if none are given, to all methods.
+func WithConnectionRateLimit(limit RateLimit, methods ...string) Option {
+	return func(o *opt) {
+		o.connRateLimits.set(limit, methods)
+	}
+}
+
+// WithUserRateLimit limits the calls of a user across all of its connections and http requests.
+// The limit applies to the given methods or, if none are given, to all methods.
+func WithUserRateLimit(limit RateLimit, methods ...string) Option {
+	return func(o *opt) {
+		o.userRateLimits.set(limit, methods)
+	}
+}
+
+// WithReadLimit sets the maximum size in bytes of a websocket message or a http request body.
+func WithReadLimit(readLimit int64) Option {
+	return func(o *opt) {
+		o.readLimit = readLimit
+	}
+}
+
+// WithMaxConnectionsPerUser rejects websocket upgrades with 429 once a user has n open connections.
+func WithMaxConnectionsPerUser(n int) Option {
+	return func(o *opt) {
+		o.maxUserConnections = n
+	}
+}
+
+// WithMetricsRegisterer sets the registerer for the handler's metrics. Defaults to prometheus.DefaultRegisterer.
+func WithMetricsRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *opt) {
+		o.metricsRegisterer = registerer
+	}
+}
+
+// WithTracerProvider sets the provider of the method spans. Defaults to the global otel provider.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(o *opt) {
+		o.tracerProvider = tracerProvider
+	}
+}
+
+// WithLogger sets the handler's logger. Every line carries the topic, connection, user and method
+// it belongs to. Defaults to a text logger on stderr at warn level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *opt) {
+		o.logger = logger
+	}
+}
+
+func newOpt(options ...Option) *opt {
+	o := &opt{
+		requestContextFunc: nil,
+		upgrader:           websocket.Upgrader{},
+		readLimit:          1 << 16,
+		metricsRegisterer:  prometheus.DefaultRegisterer,
+		tracerProvider:     otel.GetTracerProvider(),
+		logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		})),
+	}
+
+	for _, option := range options {
+		option(o)
+	}
+	o.metrics = newMetrics(o.metricsRegisterer)
+	if len(o.allowedOrigins) > 0 {
+		o.upgrader.CheckOrigin = checkOrigin(o.allowedOrigins)
+	}
+	return o
+}
+
+// authorize checks the origin and runs the authorizer. It returns nil if the request was rejected.
+func (o *opt) authorize(w http.ResponseWriter, r *http.Request) *http.Request {
+	if len(o.allowedOrigins) > 0 && !o.upgrader.CheckOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return nil
+	}
+	return authorize(w, r, o.authorizer)
+}
+
+// requestContext and requestTopic are shared by the websocket, http and events transports.
+func (o *opt) requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if o.requestContextFunc != nil {
+		ctx = o.requestContextFunc(r)
+	}
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		ctx = withPrincipal(ctx, principal)
+	}
+	return traceContext(ctx, r)
+}
+
+func (o *opt) requestTopic(r *http.Request) *string {
+	if o.subscribeTopicFunc != nil {
+		return o.subscribeTopicFunc(r)
+	}
+	return nil
+}
+
+// Method handles the calls of a json-rpc method. A *jsonrpc2.Error it returns is sent as is, e.g. with an
+// application error code and data, any other error as an internal error.
 type Method func(ctx context.Context, params []byte) (interface{}, error)
 
-type connHandler struct {
-	requestContext context.Context
-	methods        map[string]Method
-	topic          string
-	router         *router
-	resultHook     func(method string, result interface{}) interface{}
+func (o *opt) allow(method string, conn, user *limiters) bool {
+	return conn.allow(o.connRateLimits, method) && user.allow(o.userRateLimits, method)
 }
 
-func (h *connHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
-	method, ok := h.methods[req.Method]
+// call checks the rate limits, runs the method for req and returns the hooked result or a json-rpc error.
+func call(ctx context.Context, methods map[string]Method, o *opt, transport string, req *jsonrpc2.Request, conn, user *limiters) (interface{}, *jsonrpc2.Error) {
+	method, ok := methods[req.Method]
 	if !ok {
-		err := conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
+		o.metrics.calls.WithLabelValues(unknownLabel, transport, outcomeNotFound).Inc()
+		return nil, &jsonrpc2.Error{
 			Code:    jsonrpc2.CodeMethodNotFound,
 			Message: "method not found",
 			Data:    nil,
-		})
-		if err != nil {
-			log.Println("ReplyWithError err: ", err)
 		}
-		return
+	}
+	if !o.allow(req.Method, conn, user) {
+		o.metrics.calls.WithLabelValues(req.Method, transport, outcomeRateLimited).Inc()
+		return nil, rateLimitedError()
 	}
 	var params []byte
 	if req.Params != nil {
 		params = *req.Params
 	}
+
+	ctx, span := o.tracerProvider.Tracer(instrumentationName).Start(ctx, "jsonrpc2 "+req.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "jsonrpc"),
+			attribute.String("rpc.method", req.Method),
+			attribute.String("websocketjsonrpc2.transport", transport),
+		))
+	defer span.End()
+	start := time.Now()
 	result, err := method(ctx, params)
+	o.metrics.callDurations.WithLabelValues(req.Method, transport).Observe(time.Since(start).Seconds())
 	if err != nil {
-		err = conn.ReplyWithError(ctx, req.ID, &jsonrpc2.Error{
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		o.metrics.calls.WithLabelValues(req.Method, transport, outcomeError).Inc()
+		var rpcErr *jsonrpc2.Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return nil, &jsonrpc2.Error{
 			Code:    jsonrpc2.CodeInternalError,
 			Message: err.Error(),
 			Data:    nil,
-		})
+		}
+	}
+	o.metrics.calls.WithLabelValues(req.Method, transport, outcomeOK).Inc()
+
+	if o.resultHook != nil {
+		result = o.resultHook(req.Method, result)
+	}
+	return result, nil
+}
+
+type connHandler struct {
+	methods      map[string]Method
+	topic        *string
+	router       *router
+	opt          *opt
+	connLimiters *limiters
+	userLimiters *limiters
+	logger       *slog.Logger
+}
+
+func (h *connHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	logger := h.logger.With("method", req.Method)
+	if !h.router.startCall() {
+		if err := conn.ReplyWithError(ctx, req.ID, shuttingDownError()); err != nil {
+			logger.Debug("ReplyWithError", "err", err)
+		}
+		return
+	}
+	defer h.router.inflight.Done()
+
+	result, rpcErr := call(ctx, h.methods, h.opt, transportWebsocket, req, h.connLimiters, h.userLimiters)
+	if rpcErr != nil {
+		logger.Warn("call failed", "code", rpcErr.Code, "err", rpcErr.Message)
+		err := conn.ReplyWithError(ctx, req.ID, rpcErr)
 		if err != nil {
-			log.Println("ReplyWithError err: ", err)
+			logger.Warn("ReplyWithError", "err", err)
 		}
 		return
 	}
 
-	if h.resultHook != nil {
-		result = h.resultHook(req.Method, result)
+	if h.topic == nil {
+		if err := conn.Reply(ctx, req.ID, result); err != nil {
+			logger.Warn("reply", "err", err)
+		}
+		return
 	}
 
 	// also broadcast to other connections for the session
-	connections, err := h.router.getTopicConnections(h.topic)
+	h.router.notifyListeners(logger, *h.topic, req.Method, result)
+	connections, err := h.router.getTopicConnections(*h.topic)
 	if err != nil {
 		return
 	}
@@ -109,7 +301,7 @@ func (h *connHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *json
 		topicConn := topicConn
 		go func(conn *jsonrpc2.Conn) {
 			if err := conn.Reply(ctx, req.ID, result); err != nil {
-				log.Printf("conn for topic %s, reply err: %v\n", h.topic, err)
+				logger.Warn("reply to topic conn", "err", err)
 				return
 			}
 		}(topicConn)
@@ -118,20 +310,67 @@ func (h *connHandler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *json
 
 type Router interface {
 	HandlerFunc(methods map[string]Method, options ...Option) http.HandlerFunc
+	HTTPHandlerFunc(methods map[string]Method, options ...Option) http.HandlerFunc
+	EventsHandlerFunc(options ...Option) http.HandlerFunc
+	Shutdown(ctx context.Context) error
 }
 
 func NewRouter() Router {
 	return &router{
 		topicConnections: make(map[string]map[string]*jsonrpc2.Conn),
+		topicListeners:   make(map[string]map[string]chan []byte),
+		connections:      make(map[string]*jsonrpc2.Conn),
+		userLimiters: userLimiters{
+			users: make(map[string]*userLimiter),
+		},
+		done: make(chan struct{}),
 	}
 }
 
 type router struct {
 	topicConnections map[string]map[string]*jsonrpc2.Conn
+	topicListeners   map[string]map[string]chan []byte
+	userLimiters     userLimiters
+	draining         atomic.Bool
+	drainOnce        sync.Once
+	done             chan struct{}
+	inflight         sync.WaitGroup
+	// drainMu orders the start of draining with the calls and connections it waits for and disconnects.
+	drainMu     sync.Mutex
+	connections map[string]*jsonrpc2.Conn
 	sync.RWMutex
 }
 
-func (ro *router) addConnection(topic, connID string, conn *jsonrpc2.Conn) {
+// startCall counts a call in flight for Shutdown to wait for, it returns false once the router is draining.
+func (ro *router) startCall() bool {
+	ro.drainMu.Lock()
+	defer ro.drainMu.Unlock()
+	if ro.draining.Load() {
+		return false
+	}
+	ro.inflight.Add(1)
+	return true
+}
+
+// trackConnection registers a websocket connection for Shutdown to disconnect, with or without a topic.
+// It returns false once the router is draining.
+func (ro *router) trackConnection(connID string, conn *jsonrpc2.Conn) bool {
+	ro.drainMu.Lock()
+	defer ro.drainMu.Unlock()
+	if ro.draining.Load() {
+		return false
+	}
+	ro.connections[connID] = conn
+	return true
+}
+
+func (ro *router) untrackConnection(connID string) {
+	ro.drainMu.Lock()
+	defer ro.drainMu.Unlock()
+	delete(ro.connections, connID)
+}
+
+func (ro *router) addConnection(logger *slog.Logger, topic, connID string, conn *jsonrpc2.Conn) {
 	ro.Lock()
 	defer ro.Unlock()
 	_, ok := ro.topicConnections[topic]
@@ -140,10 +379,10 @@ func (ro *router) addConnection(topic, connID string, conn *jsonrpc2.Conn) {
 		ro.topicConnections[topic] = make(map[string]*jsonrpc2.Conn)
 	}
 	ro.topicConnections[topic][connID] = conn
-	log.Println("addConnection", topic, connID, len(ro.topicConnections[topic]))
+	logger.Debug("addConnection", "connections", len(ro.topicConnections[topic]))
 }
 
-func (ro *router) removeConnection(topic, connID string) {
+func (ro *router) removeConnection(logger *slog.Logger, topic, connID string) {
 	ro.Lock()
 	defer ro.Unlock()
 	connMap, ok := ro.topicConnections[topic]
@@ -160,7 +399,7 @@ func (ro *router) removeConnection(topic, connID string) {
 		delete(ro.topicConnections, topic)
 	}
 
-	log.Println("removeConnection", topic, connID, len(ro.topicConnections[topic]))
+	logger.Debug("removeConnection", "connections", len(ro.topicConnections[topic]))
 }
 
 func (ro *router) getTopicConnections(topic string) ([]*jsonrpc2.Conn, error) {
@@ -178,72 +417,87 @@ func (ro *router) getTopicConnections(topic string) ([]*jsonrpc2.Conn, error) {
 }
 
 func (ro *router) HandlerFunc(methods map[string]Method, options ...Option) http.HandlerFunc {
-	m := &connHandler{methods: methods, router: ro}
-	o := &opt{
-		requestContextFunc: nil,
-		upgrader:           websocket.Upgrader{},
-	}
-
-	for _, option := range options {
-		option(o)
-	}
-
-	m.resultHook = o.resultHook
+	o := newOpt(options...)
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		if o.requestContextFunc != nil {
-			ctx = o.requestContextFunc(r)
-		}
-		var topic *string
-		if o.subscribeTopicFunc != nil {
-			topic = o.subscribeTopicFunc(r)
-			if topic != nil {
-				m.topic = *topic
-			}
+		if ro.draining.Load() {
+			rejectDraining(w)
+			return
 		}
+		r = o.authorize(w, r)
+		if r == nil {
+			return
+		}
+		ctx := o.requestContext(r)
+		topic := o.requestTopic(r)
+
+		user := userKey(r)
+		connID := shortuuid.New()
+		logger := o.logger.With("conn_id", connID, "user_id", user)
+		if topic != nil {
+			logger = logger.With("topic", *topic)
+		}
+		userLimiters, err := ro.userLimiters.connect(user, o.maxUserConnections)
+		if err != nil {
+			logger.Warn("rejected connection", "err", err)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		defer ro.userLimiters.disconnect(user)
 
 		c, err := o.upgrader.Upgrade(w, r, nil)
 		if err != nil {
+			logger.Warn("upgrade failed", "err", err)
 			return
 		}
 		defer c.Close()
+		if o.readLimit > 0 {
+			c.SetReadLimit(o.readLimit)
+		}
+		m := &connHandler{
+			methods:      methods,
+			router:       ro,
+			topic:        topic,
+			opt:          o,
+			connLimiters: newLimiters(),
+			userLimiters: userLimiters,
+			logger:       logger,
+		}
 		jc := jsonrpc2.NewConn(ctx, websocketjsonrpc2Sg.NewObjectStream(c), m)
-		connID := shortuuid.New()
+		if !ro.trackConnection(connID, jc) {
+			// Shutdown has already disconnected the others
+			_ = jc.Notify(ctx, ReconnectMethod, nil)
+			return
+		}
+		defer ro.untrackConnection(connID)
+		route := routeLabel(r)
+		o.metrics.connections.WithLabelValues(route).Inc()
+		defer o.metrics.connections.WithLabelValues(route).Dec()
 		if topic != nil {
-			ro.addConnection(*topic, connID, jc)
+			ro.addConnection(logger, *topic, connID, jc)
+			defer ro.removeConnection(logger, *topic, connID)
 		}
 		// onConnect
-		if onConnectMethod, ok := methods[o.onConnectMethod]; ok {
+		if _, ok := methods[o.onConnectMethod]; ok {
 			id := jsonrpc2.ID{
 				Str:      o.onConnectMethod,
 				IsString: true,
 			}
-			result, err := onConnectMethod(ctx, nil)
-			if err != nil {
-				err = jc.ReplyWithError(ctx, id, &jsonrpc2.Error{
-					Code:    jsonrpc2.CodeInternalError,
-					Message: err.Error(),
-					Data:    nil,
-				})
+			result, rpcErr := call(ctx, methods, o, transportWebsocket,
+				&jsonrpc2.Request{Method: o.onConnectMethod, ID: id}, nil, nil)
+			if rpcErr != nil {
+				err = jc.ReplyWithError(ctx, id, rpcErr)
 				if err != nil {
-					log.Println("onConnectMethod, ReplyWithError err: ", err)
+					logger.Warn("onConnectMethod, ReplyWithError", "method", o.onConnectMethod, "err", err)
 				}
 				return
 			}
 
-			if o.resultHook != nil {
-				result = o.resultHook(o.onConnectMethod, result)
-			}
-
 			if err := jc.Reply(ctx, id, result); err != nil {
-				log.Printf("onConnectMethod %v, reply err: %v\n", o.onConnectMethod, err)
+				logger.Warn("onConnectMethod, reply", "method", o.onConnectMethod, "err", err)
 				return
 			}
 		}
 		<-jc.DisconnectNotify()
-		if topic != nil {
-			ro.removeConnection(*topic, connID)
-		}
 	}
 }
@@ -0,0 +1,260 @@
+package websocketjsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/lithammer/shortuuid/v3"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+var eventsKeepAlive = 15 * time.Second
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+func (ro *router) addListener(logger *slog.Logger, topic, listenerID string, ch chan []byte) {
+	ro.Lock()
+	defer ro.Unlock()
+	_, ok := ro.topicListeners[topic]
+	if !ok {
+		ro.topicListeners[topic] = make(map[string]chan []byte)
+	}
+	ro.topicListeners[topic][listenerID] = ch
+	logger.Debug("addListener", "listeners", len(ro.topicListeners[topic]))
+}
+
+func (ro *router) removeListener(logger *slog.Logger, topic, listenerID string) {
+	ro.Lock()
+	defer ro.Unlock()
+	listeners, ok := ro.topicListeners[topic]
+	if !ok {
+		return
+	}
+	delete(listeners, listenerID)
+	if len(listeners) == 0 {
+		delete(ro.topicListeners, topic)
+	}
+	logger.Debug("removeListener", "listeners", len(ro.topicListeners[topic]))
+}
+
+// notifyListeners sends the method result as a json-rpc notification to the topic's event streams.
+func (ro *router) notifyListeners(logger *slog.Logger, topic, method string, result interface{}) {
+	data, err := json.Marshal(&notification{JSONRPC: "2.0", Method: method, Params: result})
+	if err != nil {
+		logger.Error("notifyListeners, marshal", "err", err)
+		return
+	}
+	ro.RLock()
+	defer ro.RUnlock()
+	for listenerID, ch := range ro.topicListeners[topic] {
+		select {
+		case ch <- data:
+		default:
+			logger.Warn("listener is slow, dropping notification", "listener_id", listenerID, "topic", topic)
+		}
+	}
+}
+
+// broadcast notifies both the event streams and the websocket connections of a topic.
+func (ro *router) broadcast(logger *slog.Logger, r *http.Request, topic, method string, result interface{}) {
+	ro.notifyListeners(logger, topic, method, result)
+	connections, err := ro.getTopicConnections(topic)
+	if err != nil {
+		return
+	}
+	for _, conn := range connections {
+		if err := conn.Notify(r.Context(), method, result); err != nil {
+			logger.Warn("notify topic conn", "topic", topic, "err", err)
+		}
+	}
+}
+
+// HTTPHandlerFunc serves the methods as json-rpc over http POST. Both single and batch requests are supported.
+func (ro *router) HTTPHandlerFunc(methods map[string]Method, options ...Option) http.HandlerFunc {
+	o := newOpt(options...)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !ro.startCall() {
+			rejectDraining(w)
+			return
+		}
+		defer ro.inflight.Done()
+		r = o.authorize(w, r)
+		if r == nil {
+			return
+		}
+		ctx := o.requestContext(r)
+		topic := o.requestTopic(r)
+
+		// http requests count towards the user's limits without holding a connection
+		user := userKey(r)
+		logger := o.logger.With("user_id", user)
+		if topic != nil {
+			logger = logger.With("topic", *topic)
+		}
+		userLimiters := ro.userLimiters.call(user)
+		defer ro.userLimiters.callDone(user)
+
+		if o.readLimit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, o.readLimit)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(logger, w, errorResponse(jsonrpc2.ID{}, jsonrpc2.CodeParseError, err.Error()))
+			return
+		}
+		body = bytes.TrimSpace(body)
+
+		var reqs []*jsonrpc2.Request
+		batch := len(body) > 0 && body[0] == '['
+		if batch {
+			err = json.Unmarshal(body, &reqs)
+		} else {
+			req := new(jsonrpc2.Request)
+			err = json.Unmarshal(body, req)
+			reqs = append(reqs, req)
+		}
+		if err != nil {
+			writeJSON(logger, w, errorResponse(jsonrpc2.ID{}, jsonrpc2.CodeParseError, err.Error()))
+			return
+		}
+		if len(reqs) == 0 {
+			writeJSON(logger, w, errorResponse(jsonrpc2.ID{}, jsonrpc2.CodeInvalidRequest, "empty batch"))
+			return
+		}
+
+		var resps []*jsonrpc2.Response
+		for _, req := range reqs {
+			if req.Method == "" {
+				resps = append(resps, errorResponse(req.ID, jsonrpc2.CodeInvalidRequest, "method is required"))
+				continue
+			}
+			result, rpcErr := call(ctx, methods, o, transportHTTP, req, nil, userLimiters)
+			if rpcErr != nil {
+				logger.Warn("call failed", "method", req.Method, "code", rpcErr.Code, "err", rpcErr.Message)
+			}
+			if rpcErr == nil && topic != nil {
+				ro.broadcast(logger.With("method", req.Method), r, *topic, req.Method, result)
+			}
+			// notifications don't get a response
+			if req.Notif {
+				continue
+			}
+			resp := &jsonrpc2.Response{ID: req.ID, Error: rpcErr}
+			if rpcErr == nil {
+				if err := resp.SetResult(result); err != nil {
+					resp = errorResponse(req.ID, jsonrpc2.CodeInternalError, err.Error())
+				}
+			}
+			resps = append(resps, resp)
+		}
+
+		if len(resps) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if batch {
+			writeJSON(logger, w, resps)
+			return
+		}
+		writeJSON(logger, w, resps[0])
+	}
+}
+
+// EventsHandlerFunc streams the method results for the request's topic as server-sent events.
+func (ro *router) EventsHandlerFunc(options ...Option) http.HandlerFunc {
+	o := newOpt(options...)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		if ro.draining.Load() {
+			rejectDraining(w)
+			return
+		}
+		r = o.authorize(w, r)
+		if r == nil {
+			return
+		}
+		topic := o.requestTopic(r)
+		if topic == nil {
+			http.Error(w, "no topic to subscribe to", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		listenerID := shortuuid.New()
+		logger := o.logger.With("listener_id", listenerID, "user_id", userKey(r), "topic", *topic)
+		ch := make(chan []byte, 16)
+		ro.addListener(logger, *topic, listenerID, ch)
+		route := routeLabel(r)
+		o.metrics.connections.WithLabelValues(route).Inc()
+		defer func() {
+			ro.removeListener(logger, *topic, listenerID)
+			o.metrics.connections.WithLabelValues(route).Dec()
+		}()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ro.done:
+				fmt.Fprintf(w, "event: reconnect\ndata: {\"jsonrpc\":\"2.0\",\"method\":%q}\n\n", ReconnectMethod)
+				flusher.Flush()
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case data := <-ch:
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+					logger.Debug("events write", "err", err)
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func errorResponse(id jsonrpc2.ID, code int64, message string) *jsonrpc2.Response {
+	return &jsonrpc2.Response{
+		ID: id,
+		Error: &jsonrpc2.Error{
+			Code:    code,
+			Message: message,
+			Data:    nil,
+		},
+	}
+}
+
+func writeJSON(logger *slog.Logger, w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("writeJSON", "err", err)
+	}
+}
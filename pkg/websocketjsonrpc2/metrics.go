@@ -0,0 +1,91 @@
+package websocketjsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const instrumentationName = "gomodest-template/pkg/websocketjsonrpc2"
+
+// Transports a method can be called over
+const (
+	transportWebsocket = "websocket"
+	transportHTTP      = "http"
+)
+
+// Call outcomes
+const (
+	outcomeOK          = "ok"
+	outcomeError       = "error"
+	outcomeRateLimited = "rate_limited"
+	outcomeNotFound    = "not_found"
+)
+
+// unknownLabel labels the calls of methods which don't exist, their names come from the client, and the
+// connections of requests outside a chi route.
+const unknownLabel = "unknown"
+
+// routeLabel is the chi route pattern of r, e.g. /ws/todos, the topic or path may contain a user or an id.
+func routeLabel(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return unknownLabel
+}
+
+type metrics struct {
+	connections   *prometheus.GaugeVec
+	calls         *prometheus.CounterVec
+	callDurations *prometheus.HistogramVec
+}
+
+func newMetrics(registerer prometheus.Registerer) *metrics {
+	m := &metrics{
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "websocketjsonrpc2",
+			Name:      "connections_active",
+			Help:      "Number of open websocket connections and event streams per route.",
+		}, []string{"route"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "websocketjsonrpc2",
+			Name:      "calls_total",
+			Help:      "Number of method calls by method, transport and outcome.",
+		}, []string{"method", "transport", "outcome"}),
+		callDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "websocketjsonrpc2",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of method calls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "transport"}),
+	}
+	m.connections = register(registerer, m.connections).(*prometheus.GaugeVec)
+	m.calls = register(registerer, m.calls).(*prometheus.CounterVec)
+	m.callDurations = register(registerer, m.callDurations).(*prometheus.HistogramVec)
+	return m
+}
+
+// register returns the already registered collector when several handlers share a registerer.
+func register(registerer prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	err := registerer.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector
+	}
+	panic(err)
+}
+
+// traceContext continues the trace of the http request which upgraded the socket.
+func traceContext(ctx context.Context, r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
@@ -0,0 +1,196 @@
+package websocketjsonrpc2
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrRateLimited is returned to the caller when a connection or user calls methods faster than allowed.
+	ErrRateLimited = errors.New("too many requests, please slow down")
+	// ErrTooManyConnections rejects a websocket upgrade when the user has reached the maximum number of connections.
+	ErrTooManyConnections = errors.New("too many connections")
+)
+
+// json-rpc error codes for calls rejected by the router
+const (
+	// CodeRateLimited is returned for a call rejected with ErrRateLimited.
+	CodeRateLimited int64 = -32029
+	// CodeShuttingDown is returned for a call rejected with ErrShuttingDown.
+	CodeShuttingDown int64 = -32030
+)
+
+func rateLimitedError() *jsonrpc2.Error {
+	return &jsonrpc2.Error{
+		Code:    CodeRateLimited,
+		Message: ErrRateLimited.Error(),
+		Data:    nil,
+	}
+}
+
+func shuttingDownError() *jsonrpc2.Error {
+	return &jsonrpc2.Error{
+		Code:    CodeShuttingDown,
+		Message: ErrShuttingDown.Error(),
+		Data:    nil,
+	}
+}
+
+// userKey identifies the caller for per-user limits: the principal if authorized, else the remote ip.
+func userKey(r *http.Request) string {
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		return principal.ID()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimit is a token bucket which allows Burst calls at once and refills one every Interval.
+type RateLimit struct {
+	Interval time.Duration
+	Burst    int
+}
+
+type rateLimits struct {
+	all      *RateLimit
+	byMethod map[string]RateLimit
+}
+
+func (r *rateLimits) set(limit RateLimit, methods []string) {
+	if len(methods) == 0 {
+		r.all = &limit
+		return
+	}
+	if r.byMethod == nil {
+		r.byMethod = make(map[string]RateLimit)
+	}
+	for _, method := range methods {
+		r.byMethod[method] = limit
+	}
+}
+
+// bucket returns the limit for the method and the key of the bucket it's counted in.
+// A limit set for the method takes precedence over the limit for all methods.
+func (r rateLimits) bucket(method string) (RateLimit, string, bool) {
+	if limit, ok := r.byMethod[method]; ok {
+		return limit, method, true
+	}
+	if r.all != nil {
+		return *r.all, "*", true
+	}
+	return RateLimit{}, "", false
+}
+
+type limiters struct {
+	buckets map[string]*rate.Limiter
+	sync.Mutex
+}
+
+func newLimiters() *limiters {
+	return &limiters{buckets: make(map[string]*rate.Limiter)}
+}
+
+func (l *limiters) allow(limits rateLimits, method string) bool {
+	if l == nil {
+		return true
+	}
+	limit, key, ok := limits.bucket(method)
+	if !ok {
+		return true
+	}
+	l.Lock()
+	defer l.Unlock()
+	limiter, ok := l.buckets[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(limit.Interval), limit.Burst)
+		l.buckets[key] = limiter
+	}
+	return limiter.Allow()
+}
+
+// userLimiterTTL is how long a user's buckets are kept after its last connection is closed.
+var userLimiterTTL = time.Minute
+
+type userLimiter struct {
+	conns int
+	// calls are the http calls in flight, they share the buckets but aren't connections.
+	calls    int
+	lastSeen time.Time
+	limiters *limiters
+}
+
+// userLimiters tracks the connections and the rate limit buckets of each user.
+type userLimiters struct {
+	users map[string]*userLimiter
+	sync.Mutex
+}
+
+func (u *userLimiters) connect(user string, maxConnections int) (*limiters, error) {
+	u.Lock()
+	defer u.Unlock()
+	u.prune()
+	ul, ok := u.users[user]
+	if !ok {
+		ul = &userLimiter{limiters: newLimiters()}
+		u.users[user] = ul
+	}
+	if maxConnections > 0 && ul.conns >= maxConnections {
+		return nil, ErrTooManyConnections
+	}
+	ul.conns++
+	return ul.limiters, nil
+}
+
+// call returns the buckets of user for an http call, it doesn't count towards the user's connections.
+func (u *userLimiters) call(user string) *limiters {
+	u.Lock()
+	defer u.Unlock()
+	u.prune()
+	ul, ok := u.users[user]
+	if !ok {
+		ul = &userLimiter{limiters: newLimiters()}
+		u.users[user] = ul
+	}
+	ul.calls++
+	return ul.limiters
+}
+
+func (u *userLimiters) callDone(user string) {
+	u.Lock()
+	defer u.Unlock()
+	ul, ok := u.users[user]
+	if !ok {
+		return
+	}
+	ul.calls--
+	ul.lastSeen = time.Now()
+}
+
+func (u *userLimiters) disconnect(user string) {
+	u.Lock()
+	defer u.Unlock()
+	ul, ok := u.users[user]
+	if !ok {
+		return
+	}
+	ul.conns--
+	ul.lastSeen = time.Now()
+}
+
+// prune releases the buckets of users without connections or calls once userLimiterTTL has passed.
+func (u *userLimiters) prune() {
+	for user, ul := range u.users {
+		if ul.conns <= 0 && ul.calls <= 0 && time.Since(ul.lastSeen) > userLimiterTTL {
+			delete(u.users, user)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package websocketjsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func TestRateLimitsBucket(t *testing.T) {
+	var limits rateLimits
+	if _, _, ok := limits.bucket("todos/list"); ok {
+		t.Fatal("no limits set, want no bucket")
+	}
+
+	all := RateLimit{Interval: time.Second, Burst: 10}
+	update := RateLimit{Interval: time.Minute, Burst: 1}
+	limits.set(all, nil)
+	limits.set(update, []string{"todos/update"})
+
+	tests := []struct {
+		method string
+		limit  RateLimit
+		key    string
+	}{
+		{"todos/update", update, "todos/update"},
+		{"todos/list", all, "*"},
+		{"todos/delete", all, "*"},
+	}
+	for _, test := range tests {
+		limit, key, ok := limits.bucket(test.method)
+		if !ok || limit != test.limit || key != test.key {
+			t.Errorf("%s: got %+v in %q (%v), want %+v in %q", test.method, limit, key, ok, test.limit, test.key)
+		}
+	}
+}
+
+func TestLimitersAllow(t *testing.T) {
+	var limits rateLimits
+	limits.set(RateLimit{Interval: time.Hour, Burst: 2}, nil)
+	limits.set(RateLimit{Interval: time.Hour, Burst: 1}, []string{"todos/update"})
+
+	l := newLimiters()
+	if !l.allow(limits, "todos/update") {
+		t.Fatal("first update rejected")
+	}
+	if l.allow(limits, "todos/update") {
+		t.Error("update over its own burst allowed")
+	}
+	// the methods without their own limit share the "*" bucket, the update didn't use it
+	for i, method := range []string{"todos/list", "todos/delete"} {
+		if !l.allow(limits, method) {
+			t.Errorf("call %d (%s) within the global burst rejected", i, method)
+		}
+	}
+	if l.allow(limits, "todos/list") {
+		t.Error("call over the global burst allowed")
+	}
+
+	if !newLimiters().allow(rateLimits{}, "todos/list") {
+		t.Error("call without limits rejected")
+	}
+	var none *limiters
+	if !none.allow(limits, "todos/list") {
+		t.Error("call without limiters, e.g. over http for the connection limits, rejected")
+	}
+}
+
+func TestUserLimiters(t *testing.T) {
+	u := &userLimiters{users: make(map[string]*userLimiter)}
+	first, err := u.connect("alice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("alice", 2); err != ErrTooManyConnections {
+		t.Fatalf("third connection: got %v, want %v", err, ErrTooManyConnections)
+	}
+	if _, err := u.connect("bob", 2); err != nil {
+		t.Fatalf("another user's connection: %v", err)
+	}
+	// http calls share the user's buckets without counting as connections
+	if l := u.call("alice"); l != first {
+		t.Error("an http call got other buckets than the user's connections")
+	}
+	u.callDone("alice")
+	u.disconnect("alice")
+	if l, err := u.connect("alice", 2); err != nil || l != first {
+		t.Fatalf("reconnecting after a disconnect: %v", err)
+	}
+	if _, err := u.connect("alice", 0); err != nil {
+		t.Fatalf("no maximum: %v", err)
+	}
+}
+
+func TestUserLimitersPrune(t *testing.T) {
+	u := &userLimiters{users: make(map[string]*userLimiter)}
+	if _, err := u.connect("connected", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.connect("recent", 1); err != nil {
+		t.Fatal(err)
+	}
+	u.disconnect("recent")
+	if _, err := u.connect("gone", 1); err != nil {
+		t.Fatal(err)
+	}
+	u.disconnect("gone")
+	u.call("calling")
+	u.call("called")
+	u.callDone("called")
+
+	past := time.Now().Add(-2 * userLimiterTTL)
+	for _, user := range []string{"connected", "gone", "calling", "called"} {
+		u.users[user].lastSeen = past
+	}
+	u.prune()
+
+	for user, want := range map[string]bool{
+		"connected": true,
+		"recent":    true,
+		"gone":      false,
+		"calling":   true,
+		"called":    false,
+	} {
+		if _, ok := u.users[user]; ok != want {
+			t.Errorf("%s: kept %v, want %v", user, ok, want)
+		}
+	}
+}
+
+func testMethods() map[string]Method {
+	return map[string]Method{
+		"ping": func(ctx context.Context, params []byte) (interface{}, error) {
+			return "pong", nil
+		},
+	}
+}
+
+func TestHTTPHandlerRateLimited(t *testing.T) {
+	handler := NewRouter().HTTPHandlerFunc(testMethods(),
+		WithUserRateLimit(RateLimit{Interval: time.Hour, Burst: 1}),
+		WithMetricsRegisterer(prometheus.NewRegistry()))
+
+	call := func() *jsonrpc2.Response {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		resp := new(jsonrpc2.Response)
+		if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := call(); resp.Error != nil {
+		t.Fatalf("first call: %v", resp.Error)
+	}
+	resp := call()
+	if resp.Error == nil || resp.Error.Code != CodeRateLimited || resp.Error.Message != ErrRateLimited.Error() {
+		t.Fatalf("got %+v, want code %d %q", resp.Error, CodeRateLimited, ErrRateLimited)
+	}
+}
+
+func TestHandlerTooManyConnections(t *testing.T) {
+	server := httptest.NewServer(NewRouter().HandlerFunc(testMethods(),
+		WithMaxConnectionsPerUser(1),
+		WithMetricsRegisterer(prometheus.NewRegistry())))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	first, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("second connection of the same user accepted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want status %d", resp, http.StatusTooManyRequests)
+	}
+
+	// the slot is freed once the first connection is closed
+	first.Close()
+	var second *websocket.Conn
+	for i := 0; i < 50 && second == nil; i++ {
+		second, _, err = websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if second == nil {
+		t.Fatalf("reconnecting after closing the first connection: %v", err)
+	}
+	second.Close()
+}
@@ -0,0 +1,60 @@
+package websocketjsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// ErrShuttingDown rejects new connections and calls once the router has started draining.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// ReconnectMethod is the notification sent to websocket and event stream clients on shutdown.
+// Clients should reconnect, possibly to another instance.
+const ReconnectMethod = "server/reconnect"
+
+// Shutdown stops accepting connections and calls, waits for the in-flight calls to finish and then
+// asks the websocket and event stream clients to reconnect elsewhere before disconnecting them.
+func (ro *router) Shutdown(ctx context.Context) error {
+	// no call is counted nor connection tracked once draining, the wait group isn't added to while waited for
+	ro.drainMu.Lock()
+	ro.drainOnce.Do(func() {
+		ro.draining.Store(true)
+		close(ro.done)
+	})
+	ro.drainMu.Unlock()
+
+	inflight := make(chan struct{})
+	go func() {
+		ro.inflight.Wait()
+		close(inflight)
+	}()
+
+	var err error
+	select {
+	case <-inflight:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	ro.drainMu.Lock()
+	var conns []*jsonrpc2.Conn
+	for _, conn := range ro.connections {
+		conns = append(conns, conn)
+	}
+	ro.drainMu.Unlock()
+
+	for _, conn := range conns {
+		// the client may already be gone, close regardless
+		_ = conn.Notify(ctx, ReconnectMethod, nil)
+		conn.Close()
+	}
+	return err
+}
+
+func rejectDraining(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+}
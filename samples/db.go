@@ -0,0 +1,35 @@
+package samples
+
+import (
+	"database/sql"
+	"fmt"
+	"gomodest-template/config"
+	// registers the privacy policies of the todos models
+	_ "gomodest-template/samples/todos/gen/models/runtime"
+
+	"entgo.io/ent/dialect"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// OpenDB opens the configured database and returns the ent dialect of its driver.
+func OpenDB(cfg config.Config) (*sql.DB, string, error) {
+	var driverName, entDialect string
+	switch cfg.DatabaseDriver {
+	case config.SQLite:
+		// modernc.org/sqlite is pure go, so the app builds without cgo
+		driverName, entDialect = "sqlite", dialect.SQLite
+	case config.Postgres:
+		driverName, entDialect = "postgres", dialect.Postgres
+	case config.MySQL:
+		driverName, entDialect = "mysql", dialect.MySQL
+	default:
+		return nil, "", fmt.Errorf("unknown database driver %q", cfg.DatabaseDriver)
+	}
+	db, err := sql.Open(driverName, cfg.DatabaseDSN)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, entDialect, nil
+}
@@ -2,59 +2,100 @@ package samples
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gomodest-template/config"
 	glv "gomodest-template/pkg/goliveview"
+	"gomodest-template/pkg/turbo"
 	"gomodest-template/pkg/websocketjsonrpc2"
 	"gomodest-template/samples/todos"
 	"gomodest-template/samples/todos/gen/models"
-	"log"
+	"gomodest-template/samples/todos/migrations"
+	"gomodest-template/samples/todos/viewer"
+	"log/slog"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	entsql "entgo.io/ent/dialect/sql"
 	rl "github.com/adnaan/renderlayout"
 	"github.com/go-chi/chi"
 	"github.com/go-playground/form"
+	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
 	"github.com/vulcand/oxy/forward"
 	"github.com/vulcand/oxy/testutils"
 )
 
-var store = sessions.NewCookieStore([]byte(os.Getenv("my-secret-key")))
+var store *sessions.CookieStore
 
 type Result struct {
 	Method string      `json:"method"`
 	Data   interface{} `json:"data"`
 }
 
-func sessionMw(store sessions.Store) func(http.Handler) http.Handler {
-	f := func(h http.Handler) http.Handler {
-		fn := func(w http.ResponseWriter, r *http.Request) {
-			session, _ := store.Get(r, "_session_id")
-			// Set some session values.
-			key := "helloworld123"
-			session.Values["key"] = key
-			// Save it before we write to the response/return from the handler.
-			err := session.Save(r, w)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			h.ServeHTTP(w, r)
-		}
-		return http.HandlerFunc(fn)
+type sessionPrincipal string
+
+func (s sessionPrincipal) ID() string {
+	return string(s)
+}
+
+// sessionAuthorizer identifies jsonrpc2 callers by the viewer loaded from their session.
+func sessionAuthorizer(r *http.Request) (websocketjsonrpc2.Principal, error) {
+	v, ok := viewer.FromContext(r.Context())
+	if !ok {
+		return nil, websocketjsonrpc2.ErrUnauthorized
+	}
+	return sessionPrincipal(v.ID.String()), nil
+}
+
+// liveAuthorizer identifies live view callers by the viewer loaded from their session.
+func liveAuthorizer(r *http.Request) (glv.Principal, error) {
+	v, ok := viewer.FromContext(r.Context())
+	if !ok {
+		return nil, glv.ErrUnauthorized
 	}
-	return f
+	return sessionPrincipal(v.ID.String()), nil
 }
-func Router(index rl.Render) func(r chi.Router) {
+
+// Shutdown drains the live samples and releases their resources.
+type Shutdown func(ctx context.Context) error
+
+func Router(index rl.Render, cfg config.Config, logger *slog.Logger) (func(r chi.Router), Shutdown, error) {
 	ctx := context.Background()
-	db, err := models.Open("sqlite3", "file:app.db?mode=memory&cache=shared&_fk=1")
+	store = sessions.NewCookieStore([]byte(cfg.CookieSecret))
+	store.Options.HttpOnly = true
+	store.Options.SameSite = http.SameSiteLaxMode
+	store.Options.Secure = cfg.Profile == config.Prod
+	sqlDB, entDialect, err := OpenDB(cfg)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+	if err := migrateDB(ctx, sqlDB, entDialect, cfg.Migrations, logger); err != nil {
+		sqlDB.Close()
+		return nil, nil, err
 	}
-	if err := db.Schema.Create(ctx); err != nil {
-		panic(err)
+	db := models.NewClient(models.Driver(entsql.OpenDB(entDialect, sqlDB)))
+
+	accounts := &todos.Accounts{
+		DB:          db,
+		FormDecoder: form.NewDecoder(),
+		Sessions:    store,
+		LoginPath:   "/samples/login",
+		HomePath:    "/samples/todos",
+	}
+
+	// the turbo samples render only the requested frame and respond with turbo streams
+	frames, err := turbo.New(index,
+		turbo.TemplatesPath(cfg.TemplatesDir),
+		turbo.DisableCache(cfg.Debug),
+		turbo.Logger(logger.With("renderer", "turbo")))
+	if err != nil {
+		sqlDB.Close()
+		return nil, nil, err
 	}
 
 	app := todos.App{
@@ -70,12 +111,46 @@ func Router(index rl.Render) func(r chi.Router) {
 
 	d, err := json.Marshal(&data)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 	appData := string(d)
 
-	todos.StartRPCServer(db, ctx)
+	rpcServer := todos.StartRPCServer(db, func(r *http.Request) (context.Context, bool) {
+		v, ok := accounts.Viewer(r)
+		return viewer.NewContext(ctx, v), ok
+	}, cfg.RPCAddr, logger.With("server", "rpc"))
+
+	liveOptions := append(liveLimits,
+		glv.WithAuthorizer(liveAuthorizer),
+		glv.WithLogger(logger))
+	if cfg.Debug {
+		liveOptions = append(liveOptions, glv.EnableHTMLFormatting())
+	}
+	liveName := "gomodest-template"
+	liveController := glv.WebsocketController(&liveName, liveOptions...)
+	liveMultiName := "gomodest-template-multi"
+	// the users editing the same todo see each other
+	liveMultiOptions := append(liveOptions,
+		glv.WithSubscribeTopic(editTopic(db)),
+		glv.WithPresence("presence", "presence", viewerMeta))
+	liveMultiController := glv.WebsocketController(&liveMultiName, liveMultiOptions...)
+	jsonRpc2Router := websocketjsonrpc2.NewRouter()
+	templates := templatesPath(cfg.TemplatesDir)
+
+	shutdown := func(ctx context.Context) error {
+		errs := []error{
+			liveController.Shutdown(ctx),
+			liveMultiController.Shutdown(ctx),
+			jsonRpc2Router.Shutdown(ctx),
+			rpcServer.Shutdown(ctx),
+		}
+		// close the db only after the handlers using it are done
+		errs = append(errs, db.Close())
+		return errors.Join(errs...)
+	}
+
 	return func(r chi.Router) {
+		r.Use(accounts.LoadViewer)
 		r.Get("/", index("samples/list"))
 		r.Get("/sidemenu", index("samples/sidemenu"))
 
@@ -103,62 +178,109 @@ func Router(index rl.Render) func(r chi.Router) {
 				}, nil
 			}))
 		r.Get("/svelte_ws2_todos_multi/new", index("samples/svelte_todos_multi/new"))
-		fwd, _ := forward.New()
-		r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-			r.URL = testutils.ParseURI("http://localhost:3001/")
-			fwd.ServeHTTP(w, r)
-		})
 
-		r.Route("/api/todos", todosAPIRouter(db))
-
-		// todos turbo-frame sample
-		r.Route("/todos", turboFrameSPARouter(index, app))
-		// todos multi sample turbo-frame
-		r.Route("/todos_multi", turboFrameMPARouter(index, app))
+		r.Get("/signup", index("samples/accounts/signup"))
+		r.Post("/signup", index("samples/accounts/signup", accounts.Signup()))
+		r.Get("/login", index("samples/accounts/login"))
+		r.Post("/login", index("samples/accounts/login", accounts.Login()))
+		r.Post("/logout", index("samples/accounts/login", accounts.Logout()))
+
+		// every todos sample only sees the todos of the logged in user
+		r.Group(func(r chi.Router) {
+			r.Use(accounts.RequireViewer)
+			fwd, _ := forward.New()
+			r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+				r.URL = testutils.ParseURI("http://" + cfg.RPCAddr + "/")
+				fwd.ServeHTTP(w, r)
+			})
+
+			r.Route("/api/todos", todosAPIRouter(db))
+
+			// todos turbo-frame sample
+			r.Route("/todos", turboFrameSPARouter(frames, app))
+			// todos multi sample turbo-frame
+			r.Route("/todos_multi", turboFrameMPARouter(frames, app))
+
+			r.Route("/ws/todos", todosJsonRpc2WebsocketRouter(db, jsonRpc2Router, logger))
+			r.Route("/live", todosLiveRouter(db, liveController, templates, logger))
+			r.Route("/live/multi", todosLiveMultiRouter(db, liveMultiController, templates, logger))
+		})
 
-		r.Route("/ws/todos", todosJsonRpc2WebsocketRouter(db))
-		r.Route("/live", todosLiveRouter(db))
-		r.Route("/live/multi", todosLiveMultiRouter(db))
+	}, shutdown, nil
+}
 
+// migrateDB applies or checks the pending migrations as configured.
+func migrateDB(ctx context.Context, db *sql.DB, entDialect, onPending string, logger *slog.Logger) error {
+	if onPending == config.MigrationsIgnore {
+		return nil
 	}
+	migrator, err := migrations.New(db, entDialect)
+	if err != nil {
+		return err
+	}
+	if onPending == config.MigrationsApply {
+		applied, err := migrator.Up(ctx, 0)
+		for _, m := range applied {
+			logger.Info("applied migration", "migration", m.String())
+		}
+		return err
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%w: run `migrate up` or start with -migrations=%s", err, config.MigrationsApply)
+	}
+	return nil
+}
+
+// liveLimits protect the db from clients flooding the live todos with change requests.
+var liveLimits = []glv.ControllerOption{
+	glv.WithConnectionRateLimit(glv.RateLimit{Interval: 100 * time.Millisecond, Burst: 10}),
+	glv.WithConnectionRateLimit(glv.RateLimit{Interval: 500 * time.Millisecond, Burst: 4}, "validate_input"),
+	glv.WithUserRateLimit(glv.RateLimit{Interval: 50 * time.Millisecond, Burst: 30}),
+	glv.WithMaxConnectionsPerUser(10),
 }
 
-func todosLiveRouter(db *models.Client) func(r chi.Router) {
+func todosLiveRouter(db *models.Client, glvc glv.Controller, templates func(elem ...string) string, logger *slog.Logger) func(r chi.Router) {
 	return func(r chi.Router) {
-		todosEventHandler := todos.ChangeRequestHandlers{DB: db}
-		name := "gomodest-template"
-		glvc := glv.WebsocketController(&name, glv.EnableHTMLFormatting())
+		todosEventHandler := todos.ChangeRequestHandlers{DB: db, Logger: logger}
 		todosView := glvc.NewView(
-			"./templates/samples/todos_live",
+			templates("samples/todos_live"),
+			glv.WithLayout(templates("layouts/index.html")),
+			glv.WithPartials(templates("partials")),
 			glv.WithOnMount(todosEventHandler.OnListMount),
-			glv.WithChangeRequestHandlers(todosEventHandler.Map()))
+			glv.WithChangeRequestHandlers(todosEventHandler.Map()),
+			glv.WithAllowedChanges(todosEventHandler.Changes()))
 
 		r.Handle("/todos", todosView)
 	}
 }
 
-func todosLiveMultiRouter(db *models.Client) func(r chi.Router) {
+func todosLiveMultiRouter(db *models.Client, glvc glv.Controller, templates func(elem ...string) string, logger *slog.Logger) func(r chi.Router) {
 	return func(r chi.Router) {
-		todosEventHandler := todos.ChangeRequestHandlers{DB: db}
-		name := "gomodest-template-multi"
-		glvc := glv.WebsocketController(&name, glv.EnableHTMLFormatting())
-		partials := glv.WithPartials("./templates/samples/todos_live_multi/partials", "./templates/partials")
+		todosEventHandler := todos.ChangeRequestHandlers{DB: db, Logger: logger}
+		layout := glv.WithLayout(templates("layouts/index.html"))
+		partials := glv.WithPartials(templates("samples/todos_live_multi/partials"), templates("partials"))
 		todosView := glvc.NewView(
-			"./templates/samples/todos_live_multi/index.html",
+			templates("samples/todos_live_multi/index.html"),
+			layout,
 			partials,
 			glv.WithOnMount(todosEventHandler.OnListMount),
-			glv.WithChangeRequestHandlers(todosEventHandler.Map()))
+			glv.WithChangeRequestHandlers(todosEventHandler.Map()),
+			glv.WithAllowedChanges(todosEventHandler.Changes()))
 
 		newTodoView := glvc.NewView(
-			"./templates/samples/todos_live_multi/new.html",
+			templates("samples/todos_live_multi/new.html"),
+			layout,
 			partials,
-			glv.WithChangeRequestHandlers(todosEventHandler.Map()))
+			glv.WithChangeRequestHandlers(todosEventHandler.Map()),
+			glv.WithAllowedChanges(todosEventHandler.Changes()))
 
 		editTodoView := glvc.NewView(
-			"./templates/samples/todos_live_multi/edit.html",
+			templates("samples/todos_live_multi/edit.html"),
+			layout,
 			partials,
 			glv.WithOnMount(todosEventHandler.OnEditMount),
-			glv.WithChangeRequestHandlers(todosEventHandler.Map()))
+			glv.WithChangeRequestHandlers(todosEventHandler.Map()),
+			glv.WithAllowedChanges(todosEventHandler.Changes()))
 
 		r.Handle("/todos", todosView)
 		r.Handle("/todos/new", newTodoView)
@@ -166,34 +288,25 @@ func todosLiveMultiRouter(db *models.Client) func(r chi.Router) {
 	}
 }
 
-func todosJsonRpc2WebsocketRouter(db *models.Client) func(r chi.Router) {
+func todosJsonRpc2WebsocketRouter(db *models.Client, websocketjsonrpc2Router websocketjsonrpc2.Router, logger *slog.Logger) func(r chi.Router) {
 	return func(r chi.Router) {
 		todosJsonRpc2 := todos.TodosJsonRpc2{DB: db}
 		methods := map[string]websocketjsonrpc2.Method{
-			"todos/list":   todosJsonRpc2.List,
-			"todos/insert": todosJsonRpc2.Create,
-			"todos/delete": todosJsonRpc2.Delete,
-			"todos/update": todosJsonRpc2.Update,
-			"todos/get":    todosJsonRpc2.Get,
+			"todos/list":    todosJsonRpc2.List,
+			"todos/insert":  todosJsonRpc2.Create,
+			"todos/delete":  todosJsonRpc2.Delete,
+			"todos/update":  todosJsonRpc2.Update,
+			"todos/get":     todosJsonRpc2.Get,
+			"todos/reorder": todosJsonRpc2.Reorder,
 		}
 
 		options := []websocketjsonrpc2.Option{
-			websocketjsonrpc2.WithRequestContext(
-				func(r *http.Request) context.Context {
-					return context.WithValue(r.Context(), "user_id", "xyz1234")
-				}),
+			websocketjsonrpc2.WithAuthorizer(sessionAuthorizer),
+			websocketjsonrpc2.WithLogger(logger),
+			websocketjsonrpc2.WithUserRateLimit(websocketjsonrpc2.RateLimit{Interval: 200 * time.Millisecond, Burst: 20}),
+			websocketjsonrpc2.WithMaxConnectionsPerUser(10),
 			websocketjsonrpc2.WithSubscribeTopic(func(r *http.Request) *string {
-				session, _ := store.Get(r, "_session_id")
-				v, ok := session.Values["key"]
-				if !ok {
-					return nil
-				}
-				key := v.(string)
-
-				topic := fmt.Sprintf("%s_%s",
-					strings.Replace(r.URL.Path, "/", "_", -1), key)
-				log.Println("subscribed to topic", topic)
-				return &topic
+				return sessionTopic(r, r.URL.Path)
 			}),
 			//websocketjsonrpc2.WithResultHook(
 			//	func(method string, result interface{}) interface{} {
@@ -204,9 +317,14 @@ func todosJsonRpc2WebsocketRouter(db *models.Client) func(r chi.Router) {
 			//	}),
 		}
 
-		websocketjsonrpc2Router := websocketjsonrpc2.NewRouter()
+		// the http and events transports share the topic of the websocket list page
+		httpOptions := append(options, websocketjsonrpc2.WithSubscribeTopic(func(r *http.Request) *string {
+			return sessionTopic(r, "/samples/ws/todos")
+		}))
+
 		r.Route("/", func(r chi.Router) {
-			r.Use(sessionMw(store))
+			r.Post("/rpc", websocketjsonrpc2Router.HTTPHandlerFunc(methods, httpOptions...))
+			r.Get("/events", websocketjsonrpc2Router.EventsHandlerFunc(httpOptions...))
 			r.HandleFunc("/{id}",
 				websocketjsonrpc2Router.HandlerFunc(
 					methods,
@@ -224,6 +342,44 @@ func todosJsonRpc2WebsocketRouter(db *models.Client) func(r chi.Router) {
 	}
 }
 
+func sessionTopic(r *http.Request, path string) *string {
+	principal, ok := websocketjsonrpc2.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	key := principal.ID()
+
+	topic := fmt.Sprintf("%s_%s",
+		strings.Replace(path, "/", "_", -1), key)
+	return &topic
+}
+
+// editTopic subscribes the connections of a todo's live edit page to the todo, if the viewer can see it.
+// Todos are only visible to their owner, so the presence of a topic is the owner's own tabs and devices, the sample
+// never lists other users.
+func editTopic(db *models.Client) func(r *http.Request) *string {
+	return func(r *http.Request) *string {
+		uid, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return nil
+		}
+		if _, err := db.Todo.Get(r.Context(), uid); err != nil {
+			return nil
+		}
+		topic := "todo:" + uid.String()
+		return &topic
+	}
+}
+
+// viewerMeta is what's shown of a user on the todo's live edit page.
+func viewerMeta(r *http.Request) glv.M {
+	v, ok := viewer.FromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return glv.M{"email": v.Email}
+}
+
 func turboFrameSPARouter(index rl.Render, app todos.App) func(r chi.Router) {
 	return func(r chi.Router) {
 		r.Get("/", index("samples/todos/main"))
@@ -232,6 +388,16 @@ func turboFrameSPARouter(index rl.Render, app todos.App) func(r chi.Router) {
 		r.Post("/new", index("samples/todos/list", app.Create(), app.List()))
 		r.Post("/{id}/edit", index("samples/todos/list", app.Edit(), app.List()))
 		r.Post("/{id}/delete", index("samples/todos/list", app.Delete(), app.List()))
+		r.Post("/{id}/toggle", index("samples/todos/list", app.ToggleDone(), app.List()))
+		r.Post("/done", index("samples/todos/list", app.MarkAllDone(), app.List()))
+		r.Post("/clear", index("samples/todos/list", app.ClearCompleted(), app.List()))
+		r.Post("/delete", index("samples/todos/list", app.DeleteSelected(), app.List()))
+		r.Post("/{id}/restore", index("samples/todos/list", app.Restore(), app.List()))
+		// trash
+		r.Get("/trash", index("samples/todos/trash", app.Trash()))
+		r.Post("/trash/{id}/restore", index("samples/todos/trash", app.Restore(), app.Trash()))
+		r.Post("/trash/{id}/purge", index("samples/todos/trash", app.Purge(), app.Trash()))
+		r.Post("/trash/empty", index("samples/todos/trash", app.EmptyTrash(), app.Trash()))
 	}
 }
 
@@ -263,6 +429,13 @@ func todosAPIRouter(db *models.Client) func(r chi.Router) {
 	}
 }
 
+// templatesPath joins paths within the templates dir.
+func templatesPath(dir string) func(elem ...string) string {
+	return func(elem ...string) string {
+		return filepath.Join(append([]string{dir}, elem...)...)
+	}
+}
+
 func pagePath(base string) func(page string) string {
 	return func(page string) string {
 		base = strings.TrimLeft(base, "/")
@@ -0,0 +1,200 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/privacy"
+	"gomodest-template/samples/todos/gen/models/user"
+	"gomodest-template/samples/todos/viewer"
+	"net/http"
+	"net/url"
+	"strings"
+
+	rl "github.com/adnaan/renderlayout"
+	"github.com/go-playground/form"
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionName      = "_session_id"
+	sessionUserIDKey = "user_id"
+	minPasswordLen   = 8
+)
+
+var (
+	errInvalidLogin = errors.New("invalid email or password")
+	errEmailTaken   = errors.New("email is already registered")
+	// compared against for unknown emails so they take as long as wrong passwords
+	dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+)
+
+// Accounts handles signup, login and logout and resolves the viewer of a request from its session.
+type Accounts struct {
+	DB          *models.Client
+	FormDecoder *form.Decoder
+	Sessions    sessions.Store
+	// LoginPath is where RequireViewer redirects pages of logged out visitors.
+	LoginPath string
+	// HomePath is where signup and login redirect to without a next query param.
+	HomePath string
+}
+
+type credentials struct {
+	Email    string `form:"email"`
+	Password string `form:"password"`
+}
+
+// allow bypasses the user policy, signup and login run before there is a viewer.
+func allow(ctx context.Context) context.Context {
+	return privacy.DecisionContext(ctx, privacy.Allow)
+}
+
+func (a *Accounts) decodeCredentials(r *http.Request) (*credentials, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	c := new(credentials)
+	if err := a.FormDecoder.Decode(c, r.Form); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	return c, nil
+}
+
+func (a *Accounts) Signup() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		c, err := a.decodeCredentials(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(c.Email, "@") {
+			return rl.D{"email": c.Email}, fmt.Errorf("signup: %w", errors.New("email is invalid"))
+		}
+		if len(c.Password) < minPasswordLen {
+			return rl.D{"email": c.Email}, fmt.Errorf("signup: %w",
+				fmt.Errorf("password must be at least %d characters", minPasswordLen))
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(c.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		u, err := a.DB.User.Create().
+			SetEmail(c.Email).
+			SetPasswordHash(string(hash)).
+			Save(allow(r.Context()))
+		if models.IsConstraintError(err) {
+			return rl.D{"email": c.Email}, fmt.Errorf("signup: %w", errEmailTaken)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, a.login(w, r, u)
+	}
+}
+
+func (a *Accounts) Login() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		c, err := a.decodeCredentials(r)
+		if err != nil {
+			return nil, err
+		}
+		u, err := a.DB.User.Query().Where(user.Email(c.Email)).Only(allow(r.Context()))
+		if err != nil {
+			bcrypt.CompareHashAndPassword(dummyHash, []byte(c.Password))
+			return rl.D{"email": c.Email}, fmt.Errorf("login: %w", errInvalidLogin)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(c.Password)) != nil {
+			return rl.D{"email": c.Email}, fmt.Errorf("login: %w", errInvalidLogin)
+		}
+		return nil, a.login(w, r, u)
+	}
+}
+
+func (a *Accounts) Logout() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		session, _ := a.Sessions.Get(r, sessionName)
+		delete(session.Values, sessionUserIDKey)
+		if err := session.Save(r, w); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		http.Redirect(w, r, a.LoginPath, http.StatusSeeOther)
+		return nil, nil
+	}
+}
+
+func (a *Accounts) login(w http.ResponseWriter, r *http.Request, u *models.User) error {
+	session, _ := a.Sessions.Get(r, sessionName)
+	session.Values[sessionUserIDKey] = u.ID.String()
+	if err := session.Save(r, w); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	http.Redirect(w, r, a.nextPath(r), http.StatusSeeOther)
+	return nil
+}
+
+// nextPath returns the local path a visitor was sent to login from.
+func (a *Accounts) nextPath(r *http.Request) string {
+	next := r.URL.Query().Get("next")
+	if u, err := url.Parse(next); err != nil || next == "" || u.IsAbs() || u.Host != "" || !strings.HasPrefix(next, "/") {
+		return a.HomePath
+	}
+	return next
+}
+
+// Viewer returns the logged in user of the request session.
+func (a *Accounts) Viewer(r *http.Request) (viewer.Viewer, bool) {
+	session, _ := a.Sessions.Get(r, sessionName)
+	id, ok := session.Values[sessionUserIDKey].(string)
+	if !ok {
+		return viewer.Viewer{}, false
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return viewer.Viewer{}, false
+	}
+	// the user may have been deleted since the session was created
+	u, err := a.DB.User.Get(allow(r.Context()), uid)
+	if err != nil {
+		return viewer.Viewer{}, false
+	}
+	return viewer.Viewer{ID: u.ID, Email: u.Email}, true
+}
+
+// LoadViewer adds the viewer, if any, to the request context.
+func (a *Accounts) LoadViewer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := a.Viewer(r); ok {
+			r = r.WithContext(viewer.NewContext(r.Context(), v))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireViewer redirects logged out visitors of pages to login and rejects every other request.
+// It expects LoadViewer to run first.
+func (a *Accounts) RequireViewer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := viewer.FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		isPage := r.Method == http.MethodGet &&
+			r.Header.Get("Upgrade") == "" &&
+			strings.Contains(r.Header.Get("Accept"), "text/html")
+		if !isPage {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, a.LoginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// ownerID returns the id of the viewer. The todo policy denies creates without one.
+func ownerID(ctx context.Context) uuid.UUID {
+	v, _ := viewer.FromContext(ctx)
+	return v.ID
+}
@@ -1,10 +1,11 @@
 package todos
 
 import (
+	"errors"
+	"fmt"
 	"gomodest-template/samples/todos/gen/models"
 	"gomodest-template/samples/todos/gen/models/todo"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
 
@@ -13,15 +14,31 @@ import (
 	"github.com/go-chi/render"
 )
 
+// List returns the todos filtered by the Query url params, e.g. ?q=milk&status=done&created_after=2021-06-01.
+// With a limit, the url of the next page is in the Link header.
 func List(c *models.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tasks, err := c.Todo.Query().All(r.Context())
+		query, err := QueryFromURL(r.URL.Query())
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+		page, err := query.Page(r.Context(), c.Todo.Query())
+		if errors.Is(err, errInvalidQuery) {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
 		if err != nil {
 			render.Render(w, r, ErrInternal(err))
 			return
 		}
+		if page.Next != "" {
+			next := r.URL.Query()
+			next.Set("after", page.Next)
+			w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+		}
 
-		render.JSON(w, r, tasks)
+		render.JSON(w, r, page.Todos)
 	}
 }
 
@@ -40,15 +57,19 @@ func Create(c *models.Client) http.HandlerFunc {
 		newTask, err := c.Todo.Create().
 			SetStatus(todo.StatusInprogress).
 			SetText(req.Text).
+			SetOwnerID(ownerID(r.Context())).
 			Save(r.Context())
 		if err != nil {
 			render.Render(w, r, ErrInternal(err))
 			return
 		}
+		w.Header().Set("ETag", ETag(newTask))
 		render.JSON(w, r, newTask)
 	}
 }
 
+// UpdateStatus sets the status of a todo if it's still at the version of the If-Match header, any version for *.
+// A todo changed meanwhile is rejected with 412 and the current todo, a request without If-Match with 428.
 func UpdateStatus(c *models.Client) http.HandlerFunc {
 	type req struct {
 		Status string `json:"status"`
@@ -69,19 +90,35 @@ func UpdateStatus(c *models.Client) http.HandlerFunc {
 			return
 		}
 
-		updatedTask, err := c.Todo.
-			UpdateOneID(uid).
-			SetUpdatedAt(time.Now()).
-			SetStatus(todo.Status(req.Status)).
-			Save(r.Context())
+		version, err := versionOfIfMatch(r.Header.Get("If-Match"))
+		if errors.Is(err, errIfMatchRequired) {
+			render.Render(w, r, ErrPreconditionRequired(err))
+			return
+		}
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		updatedTask, err := UpdateVersion(r.Context(), c, uid, version, func(u *models.TodoUpdate) {
+			u.SetStatus(todo.Status(req.Status))
+		})
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			w.Header().Set("ETag", ETag(conflict.Current))
+			render.Render(w, r, ErrPreconditionFailed(err, conflict.Current))
+			return
+		}
 		if err != nil {
 			render.Render(w, r, ErrInternal(err))
 			return
 		}
+		w.Header().Set("ETag", ETag(updatedTask))
 		render.JSON(w, r, updatedTask)
 	}
 }
 
+// UpdateText sets the text of a todo like UpdateStatus sets its status.
 func UpdateText(c *models.Client) http.HandlerFunc {
 	type req struct {
 		Text string `json:"text"`
@@ -102,15 +139,30 @@ func UpdateText(c *models.Client) http.HandlerFunc {
 			return
 		}
 
-		updatedTask, err := c.Todo.
-			UpdateOneID(uid).
-			SetUpdatedAt(time.Now()).
-			SetText(req.Text).
-			Save(r.Context())
+		version, err := versionOfIfMatch(r.Header.Get("If-Match"))
+		if errors.Is(err, errIfMatchRequired) {
+			render.Render(w, r, ErrPreconditionRequired(err))
+			return
+		}
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err))
+			return
+		}
+
+		updatedTask, err := UpdateVersion(r.Context(), c, uid, version, func(u *models.TodoUpdate) {
+			u.SetText(req.Text)
+		})
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			w.Header().Set("ETag", ETag(conflict.Current))
+			render.Render(w, r, ErrPreconditionFailed(err, conflict.Current))
+			return
+		}
 		if err != nil {
 			render.Render(w, r, ErrInternal(err))
 			return
 		}
+		w.Header().Set("ETag", ETag(updatedTask))
 		render.JSON(w, r, updatedTask)
 	}
 }
@@ -123,7 +175,7 @@ func Delete(c *models.Client) http.HandlerFunc {
 			render.Render(w, r, ErrInternal(err))
 			return
 		}
-		err = c.Todo.DeleteOneID(uid).Exec(r.Context())
+		err = SoftDelete(r.Context(), c, uid)
 		if err != nil {
 			render.Render(w, r, ErrInternal(err))
 			return
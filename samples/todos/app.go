@@ -1,7 +1,9 @@
 package todos
 
 import (
+	"errors"
 	"fmt"
+	"gomodest-template/pkg/turbo"
 	"gomodest-template/samples/todos/gen/models"
 	"gomodest-template/samples/todos/gen/models/todo"
 	"net/http"
@@ -13,6 +15,9 @@ import (
 	"github.com/go-playground/form"
 )
 
+// errEmptyTask is shown next to the text field of a todo's form.
+var errEmptyTask = turbo.FieldErrors{"text": "a todo can't be empty"}
+
 type App struct {
 	DB          *models.Client
 	FormDecoder *form.Decoder
@@ -20,13 +25,18 @@ type App struct {
 
 func (a *App) List() rl.Data {
 	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
-		todos, err := a.DB.Todo.Query().All(r.Context())
+		query, err := QueryFromURL(r.URL.Query())
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		page, err := query.Page(r.Context(), a.DB.Todo.Query())
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
 
 		return rl.D{
-			"todos": todos,
+			"todos": page.Todos,
+			"query": query,
 		}, nil
 	}
 }
@@ -49,18 +59,19 @@ func (a *App) Create() rl.Data {
 		}
 
 		if req.Text == "" {
-			return nil, fmt.Errorf("%w", fmt.Errorf("empty task"))
+			return rl.D{"text": req.Text}, fmt.Errorf("%w", errEmptyTask)
 		}
 
-		_, err = a.DB.Todo.Create().
+		t, err := a.DB.Todo.Create().
 			SetStatus(todo.StatusInprogress).
 			SetText(req.Text).
+			SetOwnerID(ownerID(r.Context())).
 			Save(r.Context())
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
 
-		return nil, nil
+		return turbo.Stream(turbo.Prepend("todos_list", "todo", t)), nil
 	}
 }
 
@@ -82,12 +93,13 @@ func (a *App) CreateMulti() rl.Data {
 		}
 
 		if req.Text == "" {
-			return nil, fmt.Errorf("%w", fmt.Errorf("empty task"))
+			return rl.D{"text": req.Text}, fmt.Errorf("%w", errEmptyTask)
 		}
 
 		t, err := a.DB.Todo.Create().
 			SetStatus(todo.StatusInprogress).
 			SetText(req.Text).
+			SetOwnerID(ownerID(r.Context())).
 			Save(r.Context())
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
@@ -121,7 +133,8 @@ func (a *App) View() rl.Data {
 
 func (a *App) Edit() rl.Data {
 	type req struct {
-		Text string `json:"text"`
+		Text    string `json:"text"`
+		Version int
 	}
 	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
 		req := new(req)
@@ -136,7 +149,7 @@ func (a *App) Edit() rl.Data {
 		}
 
 		if req.Text == "" {
-			return nil, fmt.Errorf("%w", fmt.Errorf("empty task"))
+			return rl.D{"text": req.Text}, fmt.Errorf("%w", errEmptyTask)
 		}
 
 		id := chi.URLParam(r, "id")
@@ -145,7 +158,16 @@ func (a *App) Edit() rl.Data {
 			return nil, fmt.Errorf("%w", err)
 		}
 
-		err = a.DB.Todo.UpdateOneID(uid).SetText(req.Text).Exec(r.Context())
+		version, err := editVersion(Version(req.Version), false)
+		if err != nil {
+			return rl.D{"text": req.Text}, fmt.Errorf("%w", err)
+		}
+		_, err = EditText(r.Context(), a.DB, uid, version, req.Text)
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			// the form offers to overwrite the other change
+			return rl.D{"conflict": conflict.Current, "text": req.Text}, err
+		}
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
@@ -161,11 +183,121 @@ func (a *App) Delete() rl.Data {
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
-		err = a.DB.Todo.DeleteOneID(uid).Exec(r.Context())
+		err = SoftDelete(r.Context(), a.DB, uid)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		d := turbo.Stream(
+			turbo.Remove("todo-"+uid.String()),
+			turbo.Update("todos_notice", "undo", nil))
+		d["undo_id"] = uid
+		d["undo_lifetime"] = undoLifetime.Milliseconds()
+		return d, nil
+	}
+}
+
+func (a *App) Restore() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		uid, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		if _, err := Restore(r.Context(), a.DB, uid); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) Trash() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		todos, err := Trash(r.Context(), a.DB)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return rl.D{
+			"todos": todos,
+		}, nil
+	}
+}
+
+func (a *App) Purge() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		uid, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		if err := Purge(r.Context(), a.DB, uid); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) EmptyTrash() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		if _, err := EmptyTrash(r.Context(), a.DB); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) ToggleDone() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		uid, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		if _, err := ToggleDone(r.Context(), a.DB, uid); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) MarkAllDone() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		if _, err := MarkAllDone(r.Context(), a.DB); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) ClearCompleted() rl.Data {
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		if _, err := ClearCompleted(r.Context(), a.DB); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		return nil, nil
+	}
+}
+
+func (a *App) DeleteSelected() rl.Data {
+	type req struct {
+		IDs []string `form:"ids"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) (rl.D, error) {
+		req := new(req)
+		err := r.ParseForm()
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		err = a.FormDecoder.Decode(req, r.Form)
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
 
+		uids, err := parseIDs(req.IDs)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		if _, err := DeleteSelected(r.Context(), a.DB, uids); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
 		return nil, nil
 	}
 }
@@ -177,7 +309,7 @@ func (a *App) DeleteMulti() rl.Data {
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
-		err = a.DB.Todo.DeleteOneID(uid).Exec(r.Context())
+		err = SoftDelete(r.Context(), a.DB, uid)
 		if err != nil {
 			return nil, fmt.Errorf("%w", err)
 		}
@@ -0,0 +1,122 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errTodosChanged = errors.New("some of the selected todos no longer exist, nothing was deleted")
+
+// withTx runs fn in a transaction and rolls it back if fn fails.
+func withTx(ctx context.Context, db *models.Client, fn func(tx *models.Tx) error) error {
+	tx, err := db.Tx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			tx.Rollback()
+			panic(v)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			err = fmt.Errorf("%v: rolling back transaction: %w", err, rerr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// ToggleDone marks a todo done, or back to todo when it is done already.
+func ToggleDone(ctx context.Context, db *models.Client, id uuid.UUID) (*models.Todo, error) {
+	var toggled *models.Todo
+	err := withTx(ctx, db, func(tx *models.Tx) error {
+		t, err := tx.Todo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		status := todo.StatusDone
+		if t.Status == todo.StatusDone {
+			status = todo.StatusTodo
+		}
+		toggled, err = tx.Todo.UpdateOne(t).
+			SetStatus(status).
+			SetUpdatedAt(time.Now()).
+			Save(ctx)
+		return err
+	})
+	return toggled, err
+}
+
+// MarkAllDone marks every todo of the viewer done and returns how many changed.
+func MarkAllDone(ctx context.Context, db *models.Client) (int, error) {
+	var n int
+	err := withTx(ctx, db, func(tx *models.Tx) (err error) {
+		n, err = tx.Todo.Update().
+			Where(todo.StatusNEQ(todo.StatusDone)).
+			SetStatus(todo.StatusDone).
+			SetUpdatedAt(time.Now()).
+			Save(ctx)
+		return err
+	})
+	return n, err
+}
+
+// ClearCompleted moves the done todos of the viewer to the trash and returns how many were moved.
+func ClearCompleted(ctx context.Context, db *models.Client) (int, error) {
+	var n int
+	err := withTx(ctx, db, func(tx *models.Tx) (err error) {
+		n, err = tx.Todo.Update().
+			Where(todo.StatusEQ(todo.StatusDone)).
+			SetDeletedAt(time.Now()).
+			Save(ctx)
+		return err
+	})
+	return n, err
+}
+
+// DeleteSelected moves all the todos of ids to the trash or none of them.
+func DeleteSelected(ctx context.Context, db *models.Client, ids []uuid.UUID) (int, error) {
+	var n int
+	err := withTx(ctx, db, func(tx *models.Tx) (err error) {
+		n, err = tx.Todo.Update().
+			Where(todo.IDIn(ids...)).
+			SetDeletedAt(time.Now()).
+			Save(ctx)
+		if err != nil {
+			return err
+		}
+		if n != len(ids) {
+			return errTodosChanged
+		}
+		return nil
+	})
+	return n, err
+}
+
+// parseIDs parses the distinct ids of a multi-select.
+func parseIDs(ids []string) ([]uuid.UUID, error) {
+	uids := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		uid, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid todo id %q", id)
+		}
+		if !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+	if len(uids) == 0 {
+		return nil, errors.New("no todos selected")
+	}
+	return uids, nil
+}
@@ -0,0 +1,82 @@
+package todos
+
+import (
+	"errors"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBulk(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		other := newViewer(t, db)
+		a := createTodo(t, ctx, db, "first todo")
+		b := createTodo(t, ctx, db, "second todo")
+		createTodo(t, ctx, db, "third todo")
+
+		for _, want := range []todo.Status{todo.StatusDone, todo.StatusTodo} {
+			toggled, err := ToggleDone(ctx, db, a.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if toggled.Status != want {
+				t.Errorf("toggled to %s, want %s", toggled.Status, want)
+			}
+		}
+		if _, err := ToggleDone(other, db, a.ID); !models.IsNotFound(err) {
+			t.Errorf("toggled another viewer's todo: %v", err)
+		}
+
+		for _, want := range []int{3, 0} {
+			n, err := MarkAllDone(ctx, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != want {
+				t.Errorf("marked %d todos done, want %d", n, want)
+			}
+		}
+
+		if _, err := DeleteSelected(ctx, db, []uuid.UUID{a.ID, uuid.New()}); !errors.Is(err, errTodosChanged) {
+			t.Errorf("got %v, want %v", err, errTodosChanged)
+		}
+		if _, err := DeleteSelected(other, db, []uuid.UUID{b.ID}); !errors.Is(err, errTodosChanged) {
+			t.Errorf("deleted another viewer's todo: %v", err)
+		}
+		if n := countTodos(t, ctx, db); n != 3 {
+			t.Fatalf("got %d todos after the failed deletes, want 3", n)
+		}
+		n, err := DeleteSelected(ctx, db, []uuid.UUID{a.ID})
+		if err != nil || n != 1 {
+			t.Fatalf("deleted %d todos: %v, want 1", n, err)
+		}
+
+		n, err = ClearCompleted(ctx, db)
+		if err != nil || n != 2 {
+			t.Fatalf("cleared %d todos: %v, want 2", n, err)
+		}
+		if n := countTodos(t, ctx, db); n != 0 {
+			t.Errorf("got %d todos after clearing the completed ones, want 0", n)
+		}
+		trash, err := Trash(ctx, db)
+		if err != nil || len(trash) != 3 {
+			t.Errorf("got %d todos in the trash: %v, want 3", len(trash), err)
+		}
+	})
+}
+
+func TestParseIDs(t *testing.T) {
+	id := uuid.New()
+	uids, err := parseIDs([]string{id.String(), id.String()})
+	if err != nil || len(uids) != 1 || uids[0] != id {
+		t.Errorf("got %v, %v, want [%s]", uids, err, id)
+	}
+	for _, ids := range [][]string{nil, {"not an id"}} {
+		if _, err := parseIDs(ids); err == nil {
+			t.Errorf("%q: want an error", ids)
+		}
+	}
+}
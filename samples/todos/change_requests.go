@@ -7,7 +7,7 @@ import (
 	glv "gomodest-template/pkg/goliveview"
 	"gomodest-template/samples/todos/gen/models"
 	"gomodest-template/samples/todos/gen/models/todo"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -21,12 +21,12 @@ var (
 	errParseParams = errors.New("error parsing params")
 	errQueryDB     = errors.New("error fetching data from db")
 	errUpdateDB    = errors.New("error updating data")
-	offset         = 0
-	limit          = 3
+	pageSize       = 10
 )
 
 type ChangeRequestHandlers struct {
-	DB *models.Client
+	DB     *models.Client
+	Logger *slog.Logger
 }
 
 func (t *ChangeRequestHandlers) Map() map[string]glv.ChangeRequestHandler {
@@ -37,55 +37,88 @@ func (t *ChangeRequestHandlers) Map() map[string]glv.ChangeRequestHandler {
 		"delete":         t.Delete,
 		"get":            t.Get,
 		"validate_input": t.ValidateInput,
+		// bulk
+		"toggle":          t.ToggleDone,
+		"mark_all_done":   t.MarkAllDone,
+		"clear_completed": t.ClearCompleted,
+		"delete_selected": t.DeleteSelected,
+		"reorder":         t.Reorder,
+		"restore":         t.Restore,
+		// timers
+		"undo_countdown": t.UndoCountdown,
 	}
 }
 
-func (t *ChangeRequestHandlers) todosPageData(ctx context.Context, query Query) (glv.M, error) {
-
-	todosQuery := t.DB.Todo.
-		Query().
-		Offset(query.Offset).
-		Limit(query.Limit)
+// todosChanges re-renders the todos partial, the change most of the todos change requests make.
+var todosChanges = glv.Changes{
+	Actions:   []glv.ActionType{glv.Update},
+	Targets:   []string{"todos"},
+	Templates: []string{"todos"},
+}
 
-	if query.Order == "asc" {
-		todosQuery = todosQuery.Order(models.Asc(todo.FieldUpdatedAt))
-	} else {
-		todosQuery = todosQuery.Order(models.Desc(todo.FieldUpdatedAt))
+// Changes declares the actions, targets and templates the templates send with the change requests of Map.
+func (t *ChangeRequestHandlers) Changes() map[string]glv.Changes {
+	return map[string]glv.Changes{
+		"list": {
+			Actions:   []glv.ActionType{glv.Update, glv.Append},
+			Targets:   []string{"todos", "todos_list"},
+			Templates: []string{"todos", "todos_page"},
+		},
+		"insert": {
+			Actions:   []glv.ActionType{glv.Update, glv.Replace},
+			Targets:   []string{"todos", "new_todo"},
+			Templates: []string{"todos", "new_todo"},
+		},
+		"update": {
+			Actions:   []glv.ActionType{glv.Update, glv.Replace},
+			Targets:   []string{"todo-*", "edit_todo"},
+			Templates: []string{"todo", "edit_todo"},
+		},
+		"delete": {
+			Actions:   []glv.ActionType{glv.Update, glv.Replace},
+			Targets:   []string{"todos", "edit_todo"},
+			Templates: []string{"todos", "edit_todo"},
+		},
+		"validate_input": {
+			Actions:   []glv.ActionType{glv.Replace},
+			Targets:   []string{"validate_todo_error"},
+			Templates: []string{"validate_todo_error"},
+		},
+		"toggle":          todosChanges,
+		"mark_all_done":   todosChanges,
+		"clear_completed": todosChanges,
+		"delete_selected": todosChanges,
+		"restore":         todosChanges,
+		"reorder": {
+			Actions:   []glv.ActionType{glv.After},
+			Targets:   []string{"todos_list"},
+			Templates: []string{"todo"},
+		},
 	}
+}
 
-	todos, err := todosQuery.All(ctx)
+func (t *ChangeRequestHandlers) todosPageData(ctx context.Context, query Query) (glv.M, error) {
+	page, err := query.Page(ctx, t.DB.Todo.Query())
 	if err != nil {
-		log.Printf("err: query.all todos %v", err)
 		return nil, err
 	}
 
-	count := t.DB.Todo.Query().CountX(ctx)
-	pageData := glv.M{"todos": todos}
-
-	if count-query.Offset > query.Limit {
-		pageData["next"] = query.Offset + query.Limit
-	}
-	if (query.Offset - query.Limit) >= 0 {
-		pageData["prev"] = query.Offset - query.Limit
-		pageData["prev_enabled"] = 1
-	}
-
-	pageData["limit"] = query.Limit
-	pageData["offset"] = query.Offset
-	pageData["order"] = query.Order
-	pageData["query"] = query
-
-	return pageData, nil
+	return glv.M{
+		"todos": page.Todos,
+		"next":  page.Next,
+		"order": query.Order,
+		"query": query,
+	}, nil
 }
 
 func (t *ChangeRequestHandlers) OnListMount(r *http.Request) (int, glv.M) {
 	query := Query{
-		Offset: offset,
-		Limit:  limit,
-		Order:  "asc",
+		Limit: pageSize,
+		Order: "asc",
 	}
 	pageData, err := t.todosPageData(r.Context(), query)
 	if err != nil {
+		t.Logger.Error("mounting todos", "path", r.URL.Path, "user_id", ownerID(r.Context()), "err", err)
 		return 200, nil
 	}
 
@@ -106,7 +139,11 @@ func (t *ChangeRequestHandlers) OnEditMount(r *http.Request) (int, glv.M) {
 }
 
 func (t *ChangeRequestHandlers) List(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	// params change the filters of the current page, e.g. the search box only sends q
 	var query Query
+	if v, ok := s.Get("query"); ok {
+		query = v.(Query)
+	}
 	err := r.DecodeParams(&query)
 	if err != nil {
 		return fmt.Errorf(
@@ -115,6 +152,26 @@ func (t *ChangeRequestHandlers) List(ctx context.Context, r glv.ChangeRequest, s
 			errParseParams)
 	}
 
+	if _, err := query.Where(); err != nil {
+		return fmt.Errorf("err filter: %w", err)
+	}
+
+	if query.After != "" {
+		// infinite scroll: the request appends the page to todos_list, the cursor of the next one replaces todos_more.
+		page, err := query.Page(ctx, t.DB.Todo.Query())
+		if err != nil {
+			return fmt.Errorf("err db %v, %w", err, errQueryDB)
+		}
+		s.Change(glv.M{"todos": page.Todos})
+		s.Change(glv.M{
+			"action":   glv.Replace,
+			"target":   "todos_more",
+			"template": "todos_more",
+			"next":     page.Next,
+		})
+		return nil
+	}
+
 	pageData, err := t.todosPageData(ctx, query)
 	if err != nil {
 		return fmt.Errorf("err db %v, %w", err, errQueryDB)
@@ -124,21 +181,7 @@ func (t *ChangeRequestHandlers) List(ctx context.Context, r glv.ChangeRequest, s
 	return nil
 }
 
-func loading(enable bool) glv.M {
-	target := glv.ChangeTarget(glv.Update, "new_todo", "new_todo")
-	if enable {
-		target["loading"] = 1
-	}
-	return target
-}
-
 func (t *ChangeRequestHandlers) Create(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
-	s.Change(loading(true))
-	defer func() { s.Change(loading(false)) }()
-
-	//// fake sleep a bit to show the loading state.
-	//time.Sleep(1 * time.Second)
-
 	// decode incoming params
 	req := new(TodoRequest)
 	err := r.DecodeParams(req)
@@ -153,18 +196,17 @@ func (t *ChangeRequestHandlers) Create(ctx context.Context, r glv.ChangeRequest,
 	}
 
 	// create todo
-	_, err = t.DB.Todo.Create().
+	created, err := t.DB.Todo.Create().
 		SetStatus(todo.StatusInprogress).
 		SetText(req.Text).
+		SetOwnerID(ownerID(ctx)).
 		Save(ctx)
 	if err != nil {
 		return fmt.Errorf("err create todo %v, %w", err, errUpdateDB)
 	}
 
 	if req.Redirect {
-		s.Change(glv.M{
-			"redirect": "/samples/live/multi/todos",
-		})
+		s.Redirect("/samples/live/multi/todos")
 		return nil
 	}
 
@@ -178,7 +220,10 @@ func (t *ChangeRequestHandlers) Create(ctx context.Context, r glv.ChangeRequest,
 		return fmt.Errorf("err db %v, %w", err, errQueryDB)
 	}
 
-	s.Change(pageData)
+	s.Scope(glv.ScopeUser).Change(pageData)
+	// only the tab the todo was created from clears its form and gets ready for the next one
+	s.PushEvent("todos:created", glv.M{"id": created.ID})
+	s.Exec(glv.FocusCommand("new_todo_text"))
 	return nil
 }
 
@@ -198,18 +243,27 @@ func (t *ChangeRequestHandlers) Update(ctx context.Context, r glv.ChangeRequest,
 		return fmt.Errorf("err %w", errors.New("minimum text size is 3"))
 	}
 
-	todo, err := t.DB.Todo.
-		UpdateOneID(uid).
-		SetUpdatedAt(time.Now()).
-		SetText(req.Text).
-		Save(ctx)
+	version, err := editVersion(req.Version, req.Overwrite)
+	if err != nil {
+		return fmt.Errorf("err %w", err)
+	}
+	todo, err := EditText(ctx, t.DB, uid, version, req.Text)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		// show the other change, saving again overwrites it
+		current := structs.Map(conflict.Current)
+		current["conflict_text"] = req.Text
+		s.Change(current)
+		s.Exec(glv.ScrollIntoViewCommand("todo-text-"+req.ID), glv.FocusCommand("todo-text-"+req.ID))
+		return fmt.Errorf("err update todo %v, %w", err, ErrConflict)
+	}
 	if err != nil {
 		return fmt.Errorf("err update todo %v, %w", err, errUpdateDB)
 	}
 	s.Flash(2*time.Second, glv.M{
 		"message": "saved",
 	})
-	s.Change(structs.Map(todo))
+	s.Scope(glv.ScopeUser).Change(structs.Map(todo))
 	return nil
 }
 
@@ -225,18 +279,26 @@ func (t *ChangeRequestHandlers) Delete(ctx context.Context, r glv.ChangeRequest,
 		return fmt.Errorf("err %v, %w", err, errors.New("invalid todo id"))
 	}
 
-	err = t.DB.Todo.DeleteOneID(uid).Exec(ctx)
+	err = SoftDelete(ctx, t.DB, uid)
 	if err != nil {
 		return fmt.Errorf("err %v, %w", err, errors.New("error deleting todo"))
 	}
 
 	if req.Redirect {
-		s.Change(glv.M{
-			"redirect": "/samples/live/multi/todos",
-		})
+		s.Redirect("/samples/live/multi/todos")
 		return nil
 	}
 
+	// the undo of the last delete replaces the previous one, it counts down until it's removed
+	s.Flash(undoLifetime, glv.M{
+		"action":    glv.Update,
+		"target":    "todos_undo",
+		"template":  "todo_undo",
+		"undo_id":   uid,
+		"undo_left": int(undoLifetime.Seconds()),
+	})
+	s.Every(time.Second, "undo_countdown", glv.M{"id": uid, "until": time.Now().Add(undoLifetime)})
+
 	var query Query
 	if v, ok := s.Get("query"); ok {
 		query = v.(Query)
@@ -247,7 +309,7 @@ func (t *ChangeRequestHandlers) Delete(ctx context.Context, r glv.ChangeRequest,
 		return fmt.Errorf("err db %v, %w", err, errQueryDB)
 	}
 
-	s.Change(pageData)
+	s.Scope(glv.ScopeUser).Change(pageData)
 	return nil
 }
 
@@ -288,3 +350,164 @@ func (t *ChangeRequestHandlers) Get(ctx context.Context, r glv.ChangeRequest, s
 	s.Change(structs.Map(todo))
 	return nil
 }
+
+// refresh renders the current page of the session's list again in every tab of the user.
+func (t *ChangeRequestHandlers) refresh(ctx context.Context, s glv.Session) error {
+	var query Query
+	if v, ok := s.Get("query"); ok {
+		query = v.(Query)
+	}
+
+	pageData, err := t.todosPageData(ctx, query)
+	if err != nil {
+		return fmt.Errorf("err db %v, %w", err, errQueryDB)
+	}
+
+	s.Scope(glv.ScopeUser).Change(pageData)
+	return nil
+}
+
+func (t *ChangeRequestHandlers) ToggleDone(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	req := new(TodoRequest)
+	err := r.DecodeParams(req)
+	if err != nil {
+		return fmt.Errorf("err decode params: %v, %w", err, errParseParams)
+	}
+
+	uid, err := uuid.Parse(req.ID)
+	if err != nil {
+		return fmt.Errorf("err %v, %w", err, errors.New("invalid todo id"))
+	}
+
+	if _, err := ToggleDone(ctx, t.DB, uid); err != nil {
+		return fmt.Errorf("err toggle todo %v, %w", err, errUpdateDB)
+	}
+	return t.refresh(ctx, s)
+}
+
+func (t *ChangeRequestHandlers) MarkAllDone(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	n, err := MarkAllDone(ctx, t.DB)
+	if err != nil {
+		return fmt.Errorf("err mark all done %v, %w", err, errUpdateDB)
+	}
+	s.Flash(2*time.Second, glv.M{
+		"message": fmt.Sprintf("marked %d done", n),
+	})
+	return t.refresh(ctx, s)
+}
+
+func (t *ChangeRequestHandlers) ClearCompleted(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	n, err := ClearCompleted(ctx, t.DB)
+	if err != nil {
+		return fmt.Errorf("err clear completed %v, %w", err, errUpdateDB)
+	}
+	s.Flash(2*time.Second, glv.M{
+		"message": fmt.Sprintf("cleared %d completed", n),
+	})
+	return t.refresh(ctx, s)
+}
+
+func (t *ChangeRequestHandlers) DeleteSelected(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	req := new(struct {
+		IDs []string `json:"ids"`
+	})
+	err := r.DecodeParams(req)
+	if err != nil {
+		return fmt.Errorf("err decode params: %v, %w", err, errParseParams)
+	}
+
+	uids, err := parseIDs(req.IDs)
+	if err != nil {
+		return fmt.Errorf("err parse ids: %w", err)
+	}
+
+	if _, err := DeleteSelected(ctx, t.DB, uids); err != nil {
+		if errors.Is(err, errTodosChanged) {
+			return fmt.Errorf("err delete selected: %w", err)
+		}
+		return fmt.Errorf("err delete selected %v, %w", err, errors.New("error deleting todos"))
+	}
+	return t.refresh(ctx, s)
+}
+
+func (t *ChangeRequestHandlers) Reorder(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	req := new(ReorderRequest)
+	err := r.DecodeParams(req)
+	if err != nil {
+		return fmt.Errorf("err decode params: %v, %w", err, errParseParams)
+	}
+
+	moved, err := Reorder(ctx, t.DB, *req)
+	if err != nil {
+		if errors.Is(err, errTodosReordered) {
+			return fmt.Errorf("err reorder: %w", err)
+		}
+		return fmt.Errorf("err reorder todo %v, %w", err, errUpdateDB)
+	}
+
+	// move the element instead of rendering the list again, the dragging tab has moved it already
+	s = s.Scope(glv.ScopeUser)
+	s.Change(glv.ChangeTarget(glv.Remove, "todo-"+moved.ID.String(), ""))
+	change := structs.Map(moved)
+	switch {
+	case req.PrevID != "":
+		change["action"], change["target"] = glv.After, "todo-"+req.PrevID
+	case req.NextID != "":
+		change["action"], change["target"] = glv.Before, "todo-"+req.NextID
+	default:
+		change["action"], change["target"] = glv.Append, "todos_list"
+	}
+	change["template"] = "todo"
+	s.Change(change)
+	return nil
+}
+
+func (t *ChangeRequestHandlers) Restore(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	req := new(struct {
+		ID      string `json:"id"`
+		FlashID string `json:"flash_id"`
+	})
+	err := r.DecodeParams(req)
+	if err != nil {
+		return fmt.Errorf("err decode params: %v, %w", err, errParseParams)
+	}
+
+	uid, err := uuid.Parse(req.ID)
+	if err != nil {
+		return fmt.Errorf("err %v, %w", err, errors.New("invalid todo id"))
+	}
+
+	if _, err := Restore(ctx, t.DB, uid); err != nil {
+		return fmt.Errorf("err %v, %w", err, errors.New("error restoring todo"))
+	}
+	if req.FlashID != "" {
+		s.Change(glv.ChangeTarget(glv.Remove, req.FlashID, ""))
+	}
+	s.CancelTimer("undo_countdown")
+	return t.refresh(ctx, s)
+}
+
+// UndoCountdown updates the seconds left to undo a delete, Delete runs it every second.
+func (t *ChangeRequestHandlers) UndoCountdown(ctx context.Context, r glv.ChangeRequest, s glv.Session) error {
+	req := new(struct {
+		ID    string    `json:"id"`
+		Until time.Time `json:"until"`
+	})
+	err := r.DecodeParams(req)
+	if err != nil {
+		return fmt.Errorf("err decode params: %v, %w", err, errParseParams)
+	}
+
+	left := time.Until(req.Until).Round(time.Second)
+	if left <= 0 {
+		s.CancelTimer("undo_countdown")
+		return nil
+	}
+	s.Change(glv.M{
+		"action":   glv.Update,
+		"target":   "undo-left-" + req.ID,
+		"template": "todo_undo_left",
+		"left":     int(left.Seconds()),
+	})
+	return nil
+}
@@ -0,0 +1,108 @@
+package todos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/migrations"
+	"gomodest-template/samples/todos/viewer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// registers the privacy policies and hooks of the todos models
+	_ "gomodest-template/samples/todos/gen/models/runtime"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// testDatabases are the databases the tests run against. Sqlite runs in a temporary file, postgres and mysql
+// only when their dsn is set, e.g. TODOS_TEST_MYSQL_DSN="root:todos@tcp(localhost:3306)/todos?parseTime=true".
+var testDatabases = []struct {
+	driver  string
+	dialect string
+	dsnEnv  string
+}{
+	{"sqlite", dialect.SQLite, ""},
+	{"postgres", dialect.Postgres, "TODOS_TEST_POSTGRES_DSN"},
+	{"mysql", dialect.MySQL, "TODOS_TEST_MYSQL_DSN"},
+}
+
+// forEachDB runs test against every configured database, migrated up. The databases other than sqlite are
+// shared by the tests, which keep apart by creating their own viewers.
+func forEachDB(t *testing.T, test func(t *testing.T, db *models.Client)) {
+	for _, d := range testDatabases {
+		d := d
+		t.Run(d.driver, func(t *testing.T) {
+			dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite",
+				filepath.Join(t.TempDir(), "todos.db"))
+			if d.dsnEnv != "" {
+				dsn = os.Getenv(d.dsnEnv)
+			}
+			if dsn == "" {
+				t.Skipf("set %s to run against %s", d.dsnEnv, d.driver)
+			}
+			sqlDB, err := sql.Open(d.driver, dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { sqlDB.Close() })
+			migrator, err := migrations.New(sqlDB, d.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(context.Background(), 0); err != nil {
+				t.Fatal(err)
+			}
+			test(t, models.NewClient(models.Driver(entsql.OpenDB(d.dialect, sqlDB))))
+		})
+	}
+}
+
+// newViewer signs up a user and returns a context of them viewing their todos.
+func newViewer(t *testing.T, db *models.Client) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	u, err := db.User.Create().
+		SetEmail(uuid.NewString() + "@example.com").
+		SetPasswordHash("not a hash").
+		Save(allow(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return viewer.NewContext(ctx, viewer.Viewer{ID: u.ID, Email: u.Email})
+}
+
+func createTodo(t *testing.T, ctx context.Context, db *models.Client, text string) *models.Todo {
+	t.Helper()
+	created, err := db.Todo.Create().SetText(text).SetOwnerID(ownerID(ctx)).Save(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+// ids returns the ids of todos joined in their order, comparable with ==.
+func ids(todos []*models.Todo) string {
+	s := make([]string, len(todos))
+	for i, t := range todos {
+		s[i] = t.ID.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func countTodos(t *testing.T, ctx context.Context, db *models.Client) int {
+	t.Helper()
+	n, err := db.Todo.Query().Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
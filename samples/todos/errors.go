@@ -68,3 +68,30 @@ func ErrUnauthorized(err error) render.Renderer {
 }
 
 var ErrNotFound = &ErrResponse{HTTPStatusCode: 404, StatusText: "Resource not found."}
+
+func ErrPreconditionRequired(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 428,
+		StatusText:     "Precondition required.",
+		ErrorText:      fmt.Sprintf("%v", err),
+	}
+}
+
+// ConflictResponse is an ErrResponse with the current version of the resource.
+type ConflictResponse struct {
+	*ErrResponse
+	Current interface{} `json:"current"`
+}
+
+func ErrPreconditionFailed(err error, current interface{}) render.Renderer {
+	return &ConflictResponse{
+		ErrResponse: &ErrResponse{
+			Err:            err,
+			HTTPStatusCode: 412,
+			StatusText:     "Precondition failed.",
+			ErrorText:      fmt.Sprintf("%v", err),
+		},
+		Current: current,
+	}
+}
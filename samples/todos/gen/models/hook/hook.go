@@ -21,6 +21,19 @@ func (f TodoFunc) Mutate(ctx context.Context, m models.Mutation) (models.Value,
 	return f(ctx, mv)
 }
 
+// The UserFunc type is an adapter to allow the use of ordinary
+// function as User mutator.
+type UserFunc func(context.Context, *models.UserMutation) (models.Value, error)
+
+// Mutate calls f(ctx, m).
+func (f UserFunc) Mutate(ctx context.Context, m models.Mutation) (models.Value, error) {
+	mv, ok := m.(*models.UserMutation)
+	if !ok {
+		return nil, fmt.Errorf("unexpected mutation type %T. expect *models.UserMutation", m)
+	}
+	return f(ctx, mv)
+}
+
 // Condition is a hook condition function.
 type Condition func(context.Context, models.Mutation) bool
 
@@ -116,7 +129,6 @@ func HasFields(field string, fields ...string) Condition {
 // If executes the given hook under condition.
 //
 //	hook.If(ComputeAverage, And(HasFields(...), HasAddedFields(...)))
-//
 func If(hk models.Hook, cond Condition) models.Hook {
 	return func(next models.Mutator) models.Mutator {
 		return models.MutateFunc(func(ctx context.Context, m models.Mutation) (models.Value, error) {
@@ -131,7 +143,6 @@ func If(hk models.Hook, cond Condition) models.Hook {
 // On executes the given hook only for the given operation.
 //
 //	hook.On(Log, models.Delete|models.Create)
-//
 func On(hk models.Hook, op models.Op) models.Hook {
 	return If(hk, HasOp(op))
 }
@@ -139,7 +150,6 @@ func On(hk models.Hook, op models.Op) models.Hook {
 // Unless skips the given hook only for the given operation.
 //
 //	hook.Unless(Log, models.Update|models.UpdateOne)
-//
 func Unless(hk models.Hook, op models.Op) models.Hook {
 	return If(hk, Not(HasOp(op)))
 }
@@ -160,7 +170,6 @@ func FixedError(err error) models.Hook {
 //			Reject(models.Delete|models.Update),
 //		}
 //	}
-//
 func Reject(op models.Op) models.Hook {
 	hk := FixedError(fmt.Errorf("%s operation is not allowed", op))
 	return On(hk, op)
@@ -16,22 +16,58 @@ var (
 		{Name: "status", Type: field.TypeEnum, Nullable: true, Enums: []string{"todo", "inprogress", "done"}, Default: "todo"},
 		{Name: "created_at", Type: field.TypeTime},
 		{Name: "updated_at", Type: field.TypeTime},
+		{Name: "position", Type: field.TypeString, Default: "", SchemaType: map[string]string{"mysql": "varchar(1024) CHARACTER SET ascii COLLATE ascii_bin"}},
+		{Name: "deleted_at", Type: field.TypeTime, Nullable: true},
+		{Name: "version", Type: field.TypeInt, Default: 1},
+		{Name: "user_todos", Type: field.TypeUUID, Nullable: true},
 	}
 	// TodosTable holds the schema information for the "todos" table.
 	TodosTable = &schema.Table{
-		Name:        "todos",
-		Columns:     TodosColumns,
-		PrimaryKey:  []*schema.Column{TodosColumns[0]},
-		ForeignKeys: []*schema.ForeignKey{},
+		Name:       "todos",
+		Columns:    TodosColumns,
+		PrimaryKey: []*schema.Column{TodosColumns[0]},
+		ForeignKeys: []*schema.ForeignKey{
+			{
+				Symbol:     "todos_users_todos",
+				Columns:    []*schema.Column{TodosColumns[8]},
+				RefColumns: []*schema.Column{UsersColumns[0]},
+				OnDelete:   schema.SetNull,
+			},
+		},
+		Indexes: []*schema.Index{
+			{
+				Name:    "todo_position_user_todos",
+				Unique:  false,
+				Columns: []*schema.Column{TodosColumns[5], TodosColumns[8]},
+			},
+		},
+	}
+	// UsersColumns holds the columns for the "users" table.
+	UsersColumns = []*schema.Column{
+		{Name: "id", Type: field.TypeUUID},
+		{Name: "email", Type: field.TypeString, Unique: true},
+		{Name: "password_hash", Type: field.TypeString},
+		{Name: "created_at", Type: field.TypeTime},
+	}
+	// UsersTable holds the schema information for the "users" table.
+	UsersTable = &schema.Table{
+		Name:       "users",
+		Columns:    UsersColumns,
+		PrimaryKey: []*schema.Column{UsersColumns[0]},
 	}
 	// Tables holds all the tables in the schema.
 	Tables = []*schema.Table{
 		TodosTable,
+		UsersTable,
 	}
 )
 
 func init() {
+	TodosTable.ForeignKeys[0].RefTable = UsersTable
 	TodosTable.Annotation = &entsql.Annotation{
 		Table: "todos",
 	}
+	UsersTable.Annotation = &entsql.Annotation{
+		Table: "users",
+	}
 }
@@ -8,3 +8,6 @@ import (
 
 // Todo is the predicate function for todo builders.
 type Todo func(*sql.Selector)
+
+// User is the predicate function for user builders.
+type User func(*sql.Selector)
@@ -5,6 +5,7 @@ package models
 import (
 	"fmt"
 	"gomodest-template/samples/todos/gen/models/todo"
+	"gomodest-template/samples/todos/gen/models/user"
 	"strings"
 	"time"
 
@@ -25,6 +26,39 @@ type Todo struct {
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	// UpdatedAt holds the value of the "updated_at" field.
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Position holds the value of the "position" field.
+	Position string `json:"position,omitempty"`
+	// DeletedAt holds the value of the "deleted_at" field.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version holds the value of the "version" field.
+	Version int `json:"version,omitempty"`
+	// Edges holds the relations/edges for other nodes in the graph.
+	// The values are being populated by the TodoQuery when eager-loading is set.
+	Edges      TodoEdges `json:"edges"`
+	user_todos *uuid.UUID
+}
+
+// TodoEdges holds the relations/edges for other nodes in the graph.
+type TodoEdges struct {
+	// Owner holds the value of the owner edge.
+	Owner *User `json:"owner,omitempty"`
+	// loadedTypes holds the information for reporting if a
+	// type was loaded (or requested) in eager-loading or not.
+	loadedTypes [1]bool
+}
+
+// OwnerOrErr returns the Owner value or an error if the edge
+// was not loaded in eager-loading, or loaded but was not found.
+func (e TodoEdges) OwnerOrErr() (*User, error) {
+	if e.loadedTypes[0] {
+		if e.Owner == nil {
+			// The edge owner was loaded in eager-loading,
+			// but was not found.
+			return nil, &NotFoundError{label: user.Label}
+		}
+		return e.Owner, nil
+	}
+	return nil, &NotLoadedError{edge: "owner"}
 }
 
 // scanValues returns the types for scanning values from sql.Rows.
@@ -32,12 +66,16 @@ func (*Todo) scanValues(columns []string) ([]interface{}, error) {
 	values := make([]interface{}, len(columns))
 	for i := range columns {
 		switch columns[i] {
-		case todo.FieldText, todo.FieldStatus:
-			values[i] = &sql.NullString{}
-		case todo.FieldCreatedAt, todo.FieldUpdatedAt:
-			values[i] = &sql.NullTime{}
+		case todo.FieldVersion:
+			values[i] = new(sql.NullInt64)
+		case todo.FieldText, todo.FieldStatus, todo.FieldPosition:
+			values[i] = new(sql.NullString)
+		case todo.FieldCreatedAt, todo.FieldUpdatedAt, todo.FieldDeletedAt:
+			values[i] = new(sql.NullTime)
 		case todo.FieldID:
-			values[i] = &uuid.UUID{}
+			values[i] = new(uuid.UUID)
+		case todo.ForeignKeys[0]: // user_todos
+			values[i] = &sql.NullScanner{S: new(uuid.UUID)}
 		default:
 			return nil, fmt.Errorf("unexpected column %q for type Todo", columns[i])
 		}
@@ -83,11 +121,42 @@ func (t *Todo) assignValues(columns []string, values []interface{}) error {
 			} else if value.Valid {
 				t.UpdatedAt = value.Time
 			}
+		case todo.FieldPosition:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field position", values[i])
+			} else if value.Valid {
+				t.Position = value.String
+			}
+		case todo.FieldDeletedAt:
+			if value, ok := values[i].(*sql.NullTime); !ok {
+				return fmt.Errorf("unexpected type %T for field deleted_at", values[i])
+			} else if value.Valid {
+				t.DeletedAt = new(time.Time)
+				*t.DeletedAt = value.Time
+			}
+		case todo.FieldVersion:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field version", values[i])
+			} else if value.Valid {
+				t.Version = int(value.Int64)
+			}
+		case todo.ForeignKeys[0]:
+			if value, ok := values[i].(*sql.NullScanner); !ok {
+				return fmt.Errorf("unexpected type %T for field user_todos", values[i])
+			} else if value.Valid {
+				t.user_todos = new(uuid.UUID)
+				*t.user_todos = *value.S.(*uuid.UUID)
+			}
 		}
 	}
 	return nil
 }
 
+// QueryOwner queries the "owner" edge of the Todo entity.
+func (t *Todo) QueryOwner() *UserQuery {
+	return (&TodoClient{config: t.config}).QueryOwner(t)
+}
+
 // Update returns a builder for updating this Todo.
 // Note that you need to call Todo.Unwrap() before calling this method if this Todo
 // was returned from a transaction, and the transaction was committed or rolled back.
@@ -119,6 +188,14 @@ func (t *Todo) String() string {
 	builder.WriteString(t.CreatedAt.Format(time.ANSIC))
 	builder.WriteString(", updated_at=")
 	builder.WriteString(t.UpdatedAt.Format(time.ANSIC))
+	builder.WriteString(", position=")
+	builder.WriteString(t.Position)
+	if v := t.DeletedAt; v != nil {
+		builder.WriteString(", deleted_at=")
+		builder.WriteString(v.Format(time.ANSIC))
+	}
+	builder.WriteString(", version=")
+	builder.WriteString(fmt.Sprintf("%v", t.Version))
 	builder.WriteByte(')')
 	return builder.String()
 }
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"gomodest-template/samples/todos/gen/models/todo"
+	"gomodest-template/samples/todos/gen/models/user"
 	"time"
 
 	"entgo.io/ent/dialect/sql/sqlgraph"
@@ -69,12 +70,73 @@ func (tc *TodoCreate) SetNillableUpdatedAt(t *time.Time) *TodoCreate {
 	return tc
 }
 
+// SetPosition sets the "position" field.
+func (tc *TodoCreate) SetPosition(s string) *TodoCreate {
+	tc.mutation.SetPosition(s)
+	return tc
+}
+
+// SetNillablePosition sets the "position" field if the given value is not nil.
+func (tc *TodoCreate) SetNillablePosition(s *string) *TodoCreate {
+	if s != nil {
+		tc.SetPosition(*s)
+	}
+	return tc
+}
+
+// SetDeletedAt sets the "deleted_at" field.
+func (tc *TodoCreate) SetDeletedAt(t time.Time) *TodoCreate {
+	tc.mutation.SetDeletedAt(t)
+	return tc
+}
+
+// SetNillableDeletedAt sets the "deleted_at" field if the given value is not nil.
+func (tc *TodoCreate) SetNillableDeletedAt(t *time.Time) *TodoCreate {
+	if t != nil {
+		tc.SetDeletedAt(*t)
+	}
+	return tc
+}
+
+// SetVersion sets the "version" field.
+func (tc *TodoCreate) SetVersion(i int) *TodoCreate {
+	tc.mutation.SetVersion(i)
+	return tc
+}
+
+// SetNillableVersion sets the "version" field if the given value is not nil.
+func (tc *TodoCreate) SetNillableVersion(i *int) *TodoCreate {
+	if i != nil {
+		tc.SetVersion(*i)
+	}
+	return tc
+}
+
 // SetID sets the "id" field.
 func (tc *TodoCreate) SetID(u uuid.UUID) *TodoCreate {
 	tc.mutation.SetID(u)
 	return tc
 }
 
+// SetOwnerID sets the "owner" edge to the User entity by ID.
+func (tc *TodoCreate) SetOwnerID(id uuid.UUID) *TodoCreate {
+	tc.mutation.SetOwnerID(id)
+	return tc
+}
+
+// SetNillableOwnerID sets the "owner" edge to the User entity by ID if the given value is not nil.
+func (tc *TodoCreate) SetNillableOwnerID(id *uuid.UUID) *TodoCreate {
+	if id != nil {
+		tc = tc.SetOwnerID(*id)
+	}
+	return tc
+}
+
+// SetOwner sets the "owner" edge to the User entity.
+func (tc *TodoCreate) SetOwner(u *User) *TodoCreate {
+	return tc.SetOwnerID(u.ID)
+}
+
 // Mutation returns the TodoMutation object of the builder.
 func (tc *TodoCreate) Mutation() *TodoMutation {
 	return tc.mutation
@@ -86,7 +148,9 @@ func (tc *TodoCreate) Save(ctx context.Context) (*Todo, error) {
 		err  error
 		node *Todo
 	)
-	tc.defaults()
+	if err := tc.defaults(); err != nil {
+		return nil, err
+	}
 	if len(tc.hooks) == 0 {
 		if err = tc.check(); err != nil {
 			return nil, err
@@ -102,11 +166,17 @@ func (tc *TodoCreate) Save(ctx context.Context) (*Todo, error) {
 				return nil, err
 			}
 			tc.mutation = mutation
-			node, err = tc.sqlSave(ctx)
+			if node, err = tc.sqlSave(ctx); err != nil {
+				return nil, err
+			}
+			mutation.id = &node.ID
 			mutation.done = true
 			return node, err
 		})
 		for i := len(tc.hooks) - 1; i >= 0; i-- {
+			if tc.hooks[i] == nil {
+				return nil, fmt.Errorf("models: uninitialized hook (forgotten import models/runtime?)")
+			}
 			mut = tc.hooks[i](mut)
 		}
 		if _, err := mut.Mutate(ctx, tc.mutation); err != nil {
@@ -125,41 +195,78 @@ func (tc *TodoCreate) SaveX(ctx context.Context) *Todo {
 	return v
 }
 
+// Exec executes the query.
+func (tc *TodoCreate) Exec(ctx context.Context) error {
+	_, err := tc.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (tc *TodoCreate) ExecX(ctx context.Context) {
+	if err := tc.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
+
 // defaults sets the default values of the builder before save.
-func (tc *TodoCreate) defaults() {
+func (tc *TodoCreate) defaults() error {
 	if _, ok := tc.mutation.Status(); !ok {
 		v := todo.DefaultStatus
 		tc.mutation.SetStatus(v)
 	}
 	if _, ok := tc.mutation.CreatedAt(); !ok {
+		if todo.DefaultCreatedAt == nil {
+			return fmt.Errorf("models: uninitialized todo.DefaultCreatedAt (forgotten import models/runtime?)")
+		}
 		v := todo.DefaultCreatedAt()
 		tc.mutation.SetCreatedAt(v)
 	}
 	if _, ok := tc.mutation.UpdatedAt(); !ok {
+		if todo.DefaultUpdatedAt == nil {
+			return fmt.Errorf("models: uninitialized todo.DefaultUpdatedAt (forgotten import models/runtime?)")
+		}
 		v := todo.DefaultUpdatedAt()
 		tc.mutation.SetUpdatedAt(v)
 	}
+	if _, ok := tc.mutation.Position(); !ok {
+		v := todo.DefaultPosition
+		tc.mutation.SetPosition(v)
+	}
+	if _, ok := tc.mutation.Version(); !ok {
+		v := todo.DefaultVersion
+		tc.mutation.SetVersion(v)
+	}
 	if _, ok := tc.mutation.ID(); !ok {
+		if todo.DefaultID == nil {
+			return fmt.Errorf("models: uninitialized todo.DefaultID (forgotten import models/runtime?)")
+		}
 		v := todo.DefaultID()
 		tc.mutation.SetID(v)
 	}
+	return nil
 }
 
 // check runs all checks and user-defined validators on the builder.
 func (tc *TodoCreate) check() error {
 	if _, ok := tc.mutation.Text(); !ok {
-		return &ValidationError{Name: "text", err: errors.New("models: missing required field \"text\"")}
+		return &ValidationError{Name: "text", err: errors.New(`models: missing required field "text"`)}
 	}
 	if v, ok := tc.mutation.Status(); ok {
 		if err := todo.StatusValidator(v); err != nil {
-			return &ValidationError{Name: "status", err: fmt.Errorf("models: validator failed for field \"status\": %w", err)}
+			return &ValidationError{Name: "status", err: fmt.Errorf(`models: validator failed for field "status": %w`, err)}
 		}
 	}
 	if _, ok := tc.mutation.CreatedAt(); !ok {
-		return &ValidationError{Name: "created_at", err: errors.New("models: missing required field \"created_at\"")}
+		return &ValidationError{Name: "created_at", err: errors.New(`models: missing required field "created_at"`)}
 	}
 	if _, ok := tc.mutation.UpdatedAt(); !ok {
-		return &ValidationError{Name: "updated_at", err: errors.New("models: missing required field \"updated_at\"")}
+		return &ValidationError{Name: "updated_at", err: errors.New(`models: missing required field "updated_at"`)}
+	}
+	if _, ok := tc.mutation.Position(); !ok {
+		return &ValidationError{Name: "position", err: errors.New(`models: missing required field "position"`)}
+	}
+	if _, ok := tc.mutation.Version(); !ok {
+		return &ValidationError{Name: "version", err: errors.New(`models: missing required field "version"`)}
 	}
 	return nil
 }
@@ -167,11 +274,14 @@ func (tc *TodoCreate) check() error {
 func (tc *TodoCreate) sqlSave(ctx context.Context) (*Todo, error) {
 	_node, _spec := tc.createSpec()
 	if err := sqlgraph.CreateNode(ctx, tc.driver, _spec); err != nil {
-		if cerr, ok := isSQLConstraintError(err); ok {
-			err = cerr
+		if sqlgraph.IsConstraintError(err) {
+			err = &ConstraintError{err.Error(), err}
 		}
 		return nil, err
 	}
+	if _spec.ID.Value != nil {
+		_node.ID = _spec.ID.Value.(uuid.UUID)
+	}
 	return _node, nil
 }
 
@@ -222,6 +332,50 @@ func (tc *TodoCreate) createSpec() (*Todo, *sqlgraph.CreateSpec) {
 		})
 		_node.UpdatedAt = value
 	}
+	if value, ok := tc.mutation.Position(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeString,
+			Value:  value,
+			Column: todo.FieldPosition,
+		})
+		_node.Position = value
+	}
+	if value, ok := tc.mutation.DeletedAt(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeTime,
+			Value:  value,
+			Column: todo.FieldDeletedAt,
+		})
+		_node.DeletedAt = &value
+	}
+	if value, ok := tc.mutation.Version(); ok {
+		_spec.Fields = append(_spec.Fields, &sqlgraph.FieldSpec{
+			Type:   field.TypeInt,
+			Value:  value,
+			Column: todo.FieldVersion,
+		})
+		_node.Version = value
+	}
+	if nodes := tc.mutation.OwnerIDs(); len(nodes) > 0 {
+		edge := &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   todo.OwnerTable,
+			Columns: []string{todo.OwnerColumn},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				IDSpec: &sqlgraph.FieldSpec{
+					Type:   field.TypeUUID,
+					Column: user.FieldID,
+				},
+			},
+		}
+		for _, k := range nodes {
+			edge.Target.Nodes = append(edge.Target.Nodes, k)
+		}
+		_node.user_todos = &nodes[0]
+		_spec.Edges = append(_spec.Edges, edge)
+	}
 	return _node, _spec
 }
 
@@ -254,17 +408,19 @@ func (tcb *TodoCreateBulk) Save(ctx context.Context) ([]*Todo, error) {
 				if i < len(mutators)-1 {
 					_, err = mutators[i+1].Mutate(root, tcb.builders[i+1].mutation)
 				} else {
+					spec := &sqlgraph.BatchCreateSpec{Nodes: specs}
 					// Invoke the actual operation on the latest mutation in the chain.
-					if err = sqlgraph.BatchCreate(ctx, tcb.driver, &sqlgraph.BatchCreateSpec{Nodes: specs}); err != nil {
-						if cerr, ok := isSQLConstraintError(err); ok {
-							err = cerr
+					if err = sqlgraph.BatchCreate(ctx, tcb.driver, spec); err != nil {
+						if sqlgraph.IsConstraintError(err) {
+							err = &ConstraintError{err.Error(), err}
 						}
 					}
 				}
-				mutation.done = true
 				if err != nil {
 					return nil, err
 				}
+				mutation.id = &nodes[i].ID
+				mutation.done = true
 				return nodes[i], nil
 			})
 			for i := len(builder.hooks) - 1; i >= 0; i-- {
@@ -289,3 +445,16 @@ func (tcb *TodoCreateBulk) SaveX(ctx context.Context) []*Todo {
 	}
 	return v
 }
+
+// Exec executes the query.
+func (tcb *TodoCreateBulk) Exec(ctx context.Context) error {
+	_, err := tcb.Save(ctx)
+	return err
+}
+
+// ExecX is like Exec, but panics if an error occurs.
+func (tcb *TodoCreateBulk) ExecX(ctx context.Context) {
+	if err := tcb.Exec(ctx); err != nil {
+		panic(err)
+	}
+}
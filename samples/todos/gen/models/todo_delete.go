@@ -20,9 +20,9 @@ type TodoDelete struct {
 	mutation *TodoMutation
 }
 
-// Where adds a new predicate to the TodoDelete builder.
+// Where appends a list predicates to the TodoDelete builder.
 func (td *TodoDelete) Where(ps ...predicate.Todo) *TodoDelete {
-	td.mutation.predicates = append(td.mutation.predicates, ps...)
+	td.mutation.Where(ps...)
 	return td
 }
 
@@ -46,6 +46,9 @@ func (td *TodoDelete) Exec(ctx context.Context) (int, error) {
 			return affected, err
 		})
 		for i := len(td.hooks) - 1; i >= 0; i-- {
+			if td.hooks[i] == nil {
+				return 0, fmt.Errorf("models: uninitialized hook (forgotten import models/runtime?)")
+			}
 			mut = td.hooks[i](mut)
 		}
 		if _, err := mut.Mutate(ctx, td.mutation); err != nil {
@@ -1,3 +1,4 @@
+//go:build ignore
 // +build ignore
 
 package main
@@ -18,6 +19,8 @@ func main() {
 		IDType:  &field.TypeInfo{Type: field.TypeInt},
 		Target:  "../gen/models",
 		Package: "gomodest-template/samples/todos/gen/models",
+		// privacy scopes every todo query and mutation to its owner, entql builds the owner filter.
+		Features: []gen.Feature{gen.FeaturePrivacy, gen.FeatureEntQL},
 	})
 	if err != nil {
 		log.Fatal("running ent codegen:", err)
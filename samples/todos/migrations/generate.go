@@ -0,0 +1,101 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	entsql "entgo.io/ent/dialect/sql"
+
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/migrate"
+)
+
+var (
+	createTable = regexp.MustCompile("^CREATE TABLE (?:IF NOT EXISTS )?([`\"]?\\w+[`\"]?)")
+	createIndex = regexp.MustCompile("^CREATE (?:UNIQUE )?INDEX ([`\"]?\\w+[`\"]?) ON ([`\"]?\\w+[`\"]?)")
+	addColumn   = regexp.MustCompile("^ALTER TABLE ([`\"]?\\w+[`\"]?) ADD COLUMN ([`\"]?\\w+[`\"]?)")
+	addFK       = regexp.MustCompile("^ALTER TABLE ([`\"]?\\w+[`\"]?) ADD CONSTRAINT ([`\"]?\\w+[`\"]?) FOREIGN KEY")
+)
+
+// Generate writes the next up and down migration files of dialect into dir.
+// The up file holds the ent schema diff against a scratch database with the existing
+// migrations of dir applied; the down file reverts what it can and marks the rest as TODO.
+// It returns no files when the schema has no changes.
+func Generate(ctx context.Context, scratch *sql.DB, dialect, dir, name string) ([]string, error) {
+	migrations, err := Load(os.DirFS(dir), dialect)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: scratch, dialect: dialect, migrations: migrations}
+	if _, err := m.Up(ctx, 0); err != nil {
+		return nil, err
+	}
+
+	var diff bytes.Buffer
+	client := models.NewClient(models.Driver(entsql.OpenDB(dialect, scratch)))
+	err = client.Schema.WriteTo(ctx, &diff, migrate.WithDropIndex(true), migrate.WithDropColumn(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var up, down []string
+	for _, stmt := range strings.Split(diff.String(), "\n") {
+		stmt = strings.TrimSpace(stmt)
+		// every migration runs in its own transaction
+		if stmt == "" || stmt == "BEGIN;" || stmt == "COMMIT;" {
+			continue
+		}
+		up = append(up, stmt)
+		down = append([]string{revert(dialect, stmt)}, down...)
+	}
+	if len(up) == 0 {
+		return nil, nil
+	}
+
+	version := 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+	base := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s", version, name))
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return nil, err
+	}
+	files := []string{base + ".up.sql", base + ".down.sql"}
+	header := fmt.Sprintf("-- %04d_%s generated from the ent schema, review before applying.\n", version, name)
+	if err := os.WriteFile(files[0], []byte(header+strings.Join(up, "\n")+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(files[1], []byte(header+strings.Join(down, "\n")+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// revert returns the statement undoing stmt or a TODO comment when it can't be derived.
+func revert(dialect, stmt string) string {
+	if m := createTable.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("DROP TABLE %s;", m[1])
+	}
+	if m := createIndex.FindStringSubmatch(stmt); m != nil {
+		if dialect == "mysql" {
+			return fmt.Sprintf("DROP INDEX %s ON %s;", m[1], m[2])
+		}
+		return fmt.Sprintf("DROP INDEX %s;", m[1])
+	}
+	if m := addFK.FindStringSubmatch(stmt); m != nil {
+		if dialect == "mysql" {
+			return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", m[1], m[2])
+		}
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", m[1], m[2])
+	}
+	if m := addColumn.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", m[1], m[2])
+	}
+	return "-- TODO revert: " + stmt
+}
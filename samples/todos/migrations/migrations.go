@@ -0,0 +1,304 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FS holds the migrations of every dialect, e.g. sqlite3/0001_create_todos.up.sql.
+//
+//go:embed sqlite3 postgres mysql
+var FS embed.FS
+
+// ErrPending is returned by Check when the database is behind the migrations.
+var ErrPending = errors.New("pending migrations")
+
+// Migration is a pair of up and down sql files sharing a version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Load reads the migrations of a dialect from fsys sorted by version.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dialect, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: want a .up.sql or .down.sql suffix", file)
+		}
+		versionName := strings.SplitN(strings.TrimSuffix(base, "."+direction+".sql"), "_", 2)
+		if len(versionName) != 2 {
+			return nil, fmt.Errorf("migration %s: want <version>_<name>.%s.sql", file, direction)
+		}
+		version, err := strconv.Atoi(versionName[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", file, err)
+		}
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: versionName[1]}
+			byVersion[version] = m
+		}
+		if m.Name != versionName[1] {
+			return nil, fmt.Errorf("migration %s: version %d is also named %s", file, version, m.Name)
+		}
+		if direction == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s: missing up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations, recording the applied versions in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New returns a Migrator for the embedded migrations of dialect.
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := Load(FS, dialect)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %s", dialect)
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Status of a migration in the database.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every migration with the time it was applied at.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []Status
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns ErrPending when there are migrations to apply.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %v", ErrPending, pending)
+	}
+	return nil
+}
+
+// Up applies n pending migrations, all of them if n is 0.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+	for i, migration := range pending {
+		err := m.exec(ctx, migration.Up,
+			m.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %s up: %w", migration, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the last n applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rolledBack []Migration
+	for i := len(statuses) - 1; i >= 0 && len(rolledBack) < n; i-- {
+		migration := statuses[i].Migration
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		if strings.TrimSpace(stripComments(migration.Down)) == "" {
+			return rolledBack, fmt.Errorf("migration %s down: no statements to run", migration)
+		}
+		err := m.exec(ctx, migration.Down,
+			m.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+		if err != nil {
+			return rolledBack, fmt.Errorf("migration %s down: %w", migration, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// exec runs the migration statements and the bookkeeping query in a transaction.
+// MySQL commits ddl statements implicitly, so a failed mysql migration may be partly applied.
+func (m *Migrator) exec(ctx context.Context, statements, query string, args ...interface{}) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range split(statements) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) rebind(query string) string {
+	if m.dialect != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// split returns the statements of a migration file, one per line ending with a semicolon.
+func split(statements string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	for _, line := range strings.Split(stripComments(statements), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			stmts = append(stmts, strings.TrimSpace(stmt.String()))
+			stmt.Reset()
+		}
+	}
+	if strings.TrimSpace(stmt.String()) != "" {
+		stmts = append(stmts, strings.TrimSpace(stmt.String()))
+	}
+	return stmts
+}
+
+func stripComments(statements string) string {
+	var lines []string
+	for _, line := range strings.Split(statements, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
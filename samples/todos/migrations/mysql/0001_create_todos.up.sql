@@ -0,0 +1,2 @@
+-- 0001_create_todos generated from the ent schema, review before applying.
+CREATE TABLE IF NOT EXISTS `todos`(`id` char(36) binary NOT NULL, `text` varchar(255) NOT NULL, `status` enum('todo', 'inprogress', 'done') NULL DEFAULT 'todo', `created_at` timestamp NOT NULL, `updated_at` timestamp NOT NULL, PRIMARY KEY(`id`)) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
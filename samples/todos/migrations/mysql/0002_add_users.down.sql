@@ -0,0 +1,4 @@
+-- 0002_add_users generated from the ent schema, review before applying.
+ALTER TABLE `todos` DROP FOREIGN KEY `todos_users_todos`;
+DROP TABLE `users`;
+ALTER TABLE `todos` DROP COLUMN `user_todos`;
@@ -0,0 +1,4 @@
+-- 0002_add_users generated from the ent schema, review before applying.
+ALTER TABLE `todos` ADD COLUMN `user_todos` char(36) binary NULL;
+CREATE TABLE IF NOT EXISTS `users`(`id` char(36) binary NOT NULL, `email` varchar(255) UNIQUE NOT NULL, `password_hash` varchar(255) NOT NULL, `created_at` timestamp NOT NULL, PRIMARY KEY(`id`)) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;
+ALTER TABLE `todos` ADD CONSTRAINT `todos_users_todos` FOREIGN KEY(`user_todos`) REFERENCES `users`(`id`) ON DELETE SET NULL;
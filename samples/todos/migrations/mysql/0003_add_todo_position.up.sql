@@ -0,0 +1,5 @@
+-- 0003_add_todo_position generated from the ent schema, review before applying.
+ALTER TABLE `todos` ADD COLUMN `position` varchar(255) NOT NULL DEFAULT '';
+CREATE INDEX `todo_position_user_todos` ON `todos`(`position`, `user_todos`);
+-- existing todos keep their newest first order, see package position for the key format.
+UPDATE `todos` JOIN (SELECT `t`.`id`, (SELECT count(*) FROM `todos` AS `newer` WHERE `newer`.`updated_at` > `t`.`updated_at` OR (`newer`.`updated_at` = `t`.`updated_at` AND `newer`.`id` > `t`.`id`)) AS `n` FROM `todos` AS `t`) AS `ranked` ON `ranked`.`id` = `todos`.`id` SET `todos`.`position` = LPAD(5000000000 + `ranked`.`n`, 10, '0');
@@ -0,0 +1,2 @@
+-- 0006_widen_todo_position generated from the ent schema, review before applying.
+ALTER TABLE `todos` MODIFY COLUMN `position` varchar(255) NOT NULL DEFAULT '';
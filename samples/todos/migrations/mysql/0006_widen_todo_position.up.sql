@@ -0,0 +1,3 @@
+-- 0006_widen_todo_position generated from the ent schema, review before applying.
+-- position keys grow past varchar(255) after many moves into the same gap, ascii keeps the index small enough.
+ALTER TABLE `todos` MODIFY COLUMN `position` varchar(1024) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 0001_create_todos generated from the ent schema, review before applying.
+DROP TABLE "todos";
@@ -0,0 +1,2 @@
+-- 0001_create_todos generated from the ent schema, review before applying.
+CREATE TABLE IF NOT EXISTS "todos"("id" uuid NOT NULL, "text" varchar NOT NULL, "status" varchar NULL DEFAULT 'todo', "created_at" timestamp with time zone NOT NULL, "updated_at" timestamp with time zone NOT NULL, PRIMARY KEY("id"));
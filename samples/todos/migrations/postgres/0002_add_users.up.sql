@@ -0,0 +1,4 @@
+-- 0002_add_users generated from the ent schema, review before applying.
+ALTER TABLE "todos" ADD COLUMN "user_todos" uuid NULL;
+CREATE TABLE IF NOT EXISTS "users"("id" uuid NOT NULL, "email" varchar UNIQUE NOT NULL, "password_hash" varchar NOT NULL, "created_at" timestamp with time zone NOT NULL, PRIMARY KEY("id"));
+ALTER TABLE "todos" ADD CONSTRAINT "todos_users_todos" FOREIGN KEY("user_todos") REFERENCES "users"("id") ON DELETE SET NULL;
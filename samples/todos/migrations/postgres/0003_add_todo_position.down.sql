@@ -0,0 +1,3 @@
+-- 0003_add_todo_position generated from the ent schema, review before applying.
+DROP INDEX "todo_position_user_todos";
+ALTER TABLE "todos" DROP COLUMN "position";
@@ -0,0 +1,2 @@
+-- 0005_add_todo_version generated from the ent schema, review before applying.
+ALTER TABLE "todos" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
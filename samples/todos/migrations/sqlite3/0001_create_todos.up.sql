@@ -0,0 +1,2 @@
+-- 0001_create_todos generated from the ent schema, review before applying.
+CREATE TABLE `todos`(`id` uuid NOT NULL, `text` varchar(255) NOT NULL, `status` varchar(255) NULL DEFAULT 'todo', `created_at` datetime NOT NULL, `updated_at` datetime NOT NULL, PRIMARY KEY(`id`));
@@ -0,0 +1,3 @@
+-- 0002_add_users generated from the ent schema, review before applying.
+DROP TABLE `users`;
+ALTER TABLE `todos` DROP COLUMN `user_todos`;
@@ -0,0 +1,3 @@
+-- 0002_add_users generated from the ent schema, review before applying.
+ALTER TABLE `todos` ADD COLUMN `user_todos` uuid NULL CONSTRAINT todos_users_todos REFERENCES `users`(`id`) ON DELETE SET NULL;
+CREATE TABLE `users`(`id` uuid NOT NULL, `email` varchar(255) UNIQUE NOT NULL, `password_hash` varchar(255) NOT NULL, `created_at` datetime NOT NULL, PRIMARY KEY(`id`));
@@ -0,0 +1,5 @@
+-- 0003_add_todos_fts
+DROP TRIGGER `todos_fts_delete`;
+DROP TRIGGER `todos_fts_update`;
+DROP TRIGGER `todos_fts_insert`;
+DROP TABLE `todos_fts`;
@@ -0,0 +1,7 @@
+-- 0003_add_todos_fts indexes the todo texts for full-text search, sqlite only.
+-- The index keeps its own copy of the text keyed by the todo id, triggers keep it in sync with todos.
+CREATE VIRTUAL TABLE `todos_fts` USING fts5(`id` UNINDEXED, `text`);
+CREATE TRIGGER `todos_fts_insert` AFTER INSERT ON `todos` BEGIN INSERT INTO `todos_fts`(`id`, `text`) VALUES (new.`id`, new.`text`); END;
+CREATE TRIGGER `todos_fts_update` AFTER UPDATE OF `text` ON `todos` BEGIN UPDATE `todos_fts` SET `text` = new.`text` WHERE `id` = old.`id`; END;
+CREATE TRIGGER `todos_fts_delete` AFTER DELETE ON `todos` BEGIN DELETE FROM `todos_fts` WHERE `id` = old.`id`; END;
+INSERT INTO `todos_fts`(`id`, `text`) SELECT `id`, `text` FROM `todos`;
@@ -0,0 +1,5 @@
+-- 0004_add_todo_position generated from the ent schema, review before applying.
+ALTER TABLE `todos` ADD COLUMN `position` varchar(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS `todo_position_user_todos` ON `todos`(`position`, `user_todos`);
+-- existing todos keep their newest first order, see package position for the key format.
+UPDATE `todos` SET `position` = printf('%010d', 5000000000 + (SELECT count(*) FROM `todos` AS `newer` WHERE `newer`.`updated_at` > `todos`.`updated_at` OR (`newer`.`updated_at` = `todos`.`updated_at` AND `newer`.`id` > `todos`.`id`)));
@@ -0,0 +1,2 @@
+-- 0005_soft_delete_todos generated from the ent schema, review before applying.
+ALTER TABLE `todos` DROP COLUMN `deleted_at`;
@@ -0,0 +1,2 @@
+-- 0006_add_todo_version generated from the ent schema, review before applying.
+ALTER TABLE `todos` ADD COLUMN `version` integer NOT NULL DEFAULT 1;
@@ -0,0 +1,140 @@
+// Package position generates fractional index keys: strings that sort in list order, so that
+// moving an item between two others only changes the key of the moved item.
+//
+// A key is a 10 digit decimal integer part followed by an optional base 36 fraction without trailing zeros,
+// e.g. 5000000000 or 5000000000i. Only digits and lowercase letters are used so that keys sort the same
+// under the binary, case insensitive and locale collations of sqlite, mysql and postgres.
+package position
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// First is the key of the first item of an empty list.
+	First  = "5000000000"
+	intLen = len(First)
+	digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// ErrOrder is returned by Between when a does not sort before b.
+var ErrOrder = errors.New("position: keys out of order")
+
+// Between returns a key sorting after a and before b. An empty a is the start of the list, an empty b its end.
+func Between(a, b string) (string, error) {
+	for _, key := range []string{a, b} {
+		if err := validate(key); err != nil {
+			return "", err
+		}
+	}
+	if a != "" && b != "" && a >= b {
+		return "", fmt.Errorf("%w: %q >= %q", ErrOrder, a, b)
+	}
+
+	switch {
+	case a == "" && b == "":
+		return First, nil
+	case a == "":
+		ib, fb := split(b)
+		if fb != "" {
+			return ib, nil
+		}
+		return addInt(ib, -1)
+	case b == "":
+		ia, _ := split(a)
+		return addInt(ia, 1)
+	}
+
+	ia, fa := split(a)
+	ib, fb := split(b)
+	if ia == ib {
+		return ia + midpoint(fa, fb), nil
+	}
+	na, _ := strconv.ParseInt(ia, 10, 64)
+	nb, _ := strconv.ParseInt(ib, 10, 64)
+	if nb-na > 1 {
+		return formatInt((na + nb) / 2), nil
+	}
+	return ia + midpoint(fa, ""), nil
+}
+
+func validate(key string) error {
+	if key == "" {
+		return nil
+	}
+	i, f := split(key)
+	if len(i) != intLen || strings.Trim(i, "0123456789") != "" {
+		return fmt.Errorf("position: invalid key %q", key)
+	}
+	if strings.Trim(f, digits) != "" || strings.HasSuffix(f, "0") {
+		return fmt.Errorf("position: invalid key %q", key)
+	}
+	return nil
+}
+
+func split(key string) (string, string) {
+	if len(key) < intLen {
+		return key, ""
+	}
+	return key[:intLen], key[intLen:]
+}
+
+func addInt(i string, delta int64) (string, error) {
+	n, _ := strconv.ParseInt(i, 10, 64)
+	n += delta
+	if n < 0 || len(formatInt(n)) > intLen {
+		return "", fmt.Errorf("position: no key left after %q", i)
+	}
+	return formatInt(n), nil
+}
+
+func formatInt(n int64) string {
+	return fmt.Sprintf("%0*d", intLen, n)
+}
+
+// midpoint returns a fraction between the fractions a and b, an empty b is 1.
+func midpoint(a, b string) string {
+	if b != "" {
+		// skip the common prefix, a is padded with zeros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(tail(a, n), b[n:])
+		}
+	}
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(digits, a[0])
+	}
+	db := len(digits)
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db+1)/2])
+	}
+	// consecutive digits
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(tail(a, 1), "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+func tail(s string, i int) string {
+	if i < len(s) {
+		return s[i:]
+	}
+	return ""
+}
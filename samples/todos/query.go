@@ -0,0 +1,236 @@
+package todos
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/predicate"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+)
+
+// dateLayout is the value format of html date inputs.
+const dateLayout = "2006-01-02"
+
+// errInvalidQuery wraps the errors of query params a client got wrong.
+var errInvalidQuery = errors.New("invalid query")
+
+// Query selects a page of todos. Dates are RFC3339 timestamps or 2006-01-02 days,
+// a day in a *Before field includes the whole day. After is the Next cursor of the previous page,
+// a zero Limit returns every todo. Order is desc or asc by updated_at, or manual by position.
+type Query struct {
+	After         string `json:"after,omitempty"`
+	Limit         int    `json:"limit"`
+	Order         string `json:"order,omitempty"`
+	Search        string `json:"q,omitempty"`
+	Status        string `json:"status,omitempty"`
+	CreatedAfter  string `json:"created_after,omitempty"`
+	CreatedBefore string `json:"created_before,omitempty"`
+	UpdatedAfter  string `json:"updated_after,omitempty"`
+	UpdatedBefore string `json:"updated_before,omitempty"`
+}
+
+// QueryFromURL reads a Query from url query params named like its json fields.
+func QueryFromURL(values url.Values) (Query, error) {
+	query := Query{
+		After:         values.Get("after"),
+		Order:         values.Get("order"),
+		Search:        values.Get("q"),
+		Status:        values.Get("status"),
+		CreatedAfter:  values.Get("created_after"),
+		CreatedBefore: values.Get("created_before"),
+		UpdatedAfter:  values.Get("updated_after"),
+		UpdatedBefore: values.Get("updated_before"),
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("%w: limit must be a positive number, got %q", errInvalidQuery, limit)
+		}
+		query.Limit = n
+	}
+	return query, nil
+}
+
+// Where returns the filters of the query.
+func (q Query) Where() ([]predicate.Todo, error) {
+	var where []predicate.Todo
+	if search := strings.TrimSpace(q.Search); search != "" {
+		where = append(where, textMatches(search))
+	}
+	if q.Status != "" {
+		status := todo.Status(q.Status)
+		if err := todo.StatusValidator(status); err != nil {
+			return nil, fmt.Errorf("%w: status must be one of todo, inprogress or done, got %q", errInvalidQuery, q.Status)
+		}
+		where = append(where, todo.StatusEQ(status))
+	}
+	dates := []struct {
+		name   string
+		value  string
+		before bool
+		p      func(time.Time) predicate.Todo
+	}{
+		{"created_after", q.CreatedAfter, false, todo.CreatedAtGTE},
+		{"created_before", q.CreatedBefore, true, todo.CreatedAtLT},
+		{"updated_after", q.UpdatedAfter, false, todo.UpdatedAtGTE},
+		{"updated_before", q.UpdatedBefore, true, todo.UpdatedAtLT},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseDate(d.value, d.before)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", errInvalidQuery, d.name, err)
+		}
+		where = append(where, d.p(t))
+	}
+	return where, nil
+}
+
+// Page is a page of todos ordered by (updated_at, id) or (position, id). Next is the cursor of the following page, empty on the last one.
+type Page struct {
+	Todos []*models.Todo `json:"todos"`
+	Next  string         `json:"next,omitempty"`
+}
+
+// cursor is the position of a todo in the order it was listed in. It is opaque to clients.
+type cursor struct {
+	UpdatedAt time.Time `json:"u"`
+	Position  string    `json:"p,omitempty"`
+	ID        uuid.UUID `json:"i"`
+	Order     string    `json:"o"`
+}
+
+func (c cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseCursor(s, order string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.Order != order {
+		return c, fmt.Errorf("%w: after is not a cursor of a %s page", errInvalidQuery, order)
+	}
+	return c, nil
+}
+
+// next matches the todos listed after the cursor.
+func (c cursor) next() predicate.Todo {
+	switch c.Order {
+	case "manual":
+		return todo.Or(
+			todo.PositionGT(c.Position),
+			todo.And(todo.PositionEQ(c.Position), todo.IDGT(c.ID)),
+		)
+	case "asc":
+		return todo.Or(
+			todo.UpdatedAtGT(c.UpdatedAt),
+			todo.And(todo.UpdatedAtEQ(c.UpdatedAt), todo.IDGT(c.ID)),
+		)
+	default:
+		return todo.Or(
+			todo.UpdatedAtLT(c.UpdatedAt),
+			todo.And(todo.UpdatedAtEQ(c.UpdatedAt), todo.IDLT(c.ID)),
+		)
+	}
+}
+
+// Page fetches the page of todosQuery selected by the query. Rows inserted or deleted before the cursor
+// don't shift the page like an offset would.
+func (q Query) Page(ctx context.Context, todosQuery *models.TodoQuery) (*Page, error) {
+	where, err := q.Where()
+	if err != nil {
+		return nil, err
+	}
+	todosQuery = todosQuery.Where(where...)
+
+	order := q.Order
+	switch order {
+	case "manual":
+		todosQuery = todosQuery.Order(models.Asc(todo.FieldPosition, todo.FieldID))
+	case "asc":
+		todosQuery = todosQuery.Order(models.Asc(todo.FieldUpdatedAt, todo.FieldID))
+	case "", "desc":
+		order = "desc"
+		todosQuery = todosQuery.Order(models.Desc(todo.FieldUpdatedAt, todo.FieldID))
+	default:
+		return nil, fmt.Errorf("%w: order must be asc, desc or manual, got %q", errInvalidQuery, q.Order)
+	}
+
+	if q.After != "" {
+		after, err := parseCursor(q.After, order)
+		if err != nil {
+			return nil, err
+		}
+		todosQuery = todosQuery.Where(after.next())
+	}
+	if q.Limit > 0 {
+		// one more to know if there is a next page
+		todosQuery = todosQuery.Limit(q.Limit + 1)
+	}
+
+	todos, err := todosQuery.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Todos: todos}
+	if q.Limit > 0 && len(todos) > q.Limit {
+		page.Todos = todos[:q.Limit]
+		last := page.Todos[q.Limit-1]
+		page.Next = cursor{UpdatedAt: last.UpdatedAt, Position: last.Position, ID: last.ID, Order: order}.String()
+	}
+	return page, nil
+}
+
+func parseDate(value string, before bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("want a RFC3339 time or a %s date, got %q", dateLayout, value)
+	}
+	if before {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// textMatches matches todos containing every word of search. On sqlite the words are matched as
+// word prefixes with the todos_fts index kept in sync by the triggers of 0003_add_todos_fts.
+func textMatches(search string) predicate.Todo {
+	return predicate.Todo(func(s *sql.Selector) {
+		if s.Dialect() != dialect.SQLite {
+			for _, word := range strings.Fields(search) {
+				s.Where(sql.ContainsFold(s.C(todo.FieldText), word))
+			}
+			return
+		}
+		s.Where(sql.ExprP(s.C(todo.FieldID)+" IN (SELECT `id` FROM `todos_fts` WHERE `todos_fts` MATCH ?)", ftsQuery(search)))
+	})
+}
+
+// ftsQuery quotes every word so that fts5 operators in search are matched literally.
+func ftsQuery(search string) string {
+	words := strings.Fields(search)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,114 @@
+package todos
+
+import (
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"testing"
+	"time"
+)
+
+func TestQueryPage(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		createTodo(t, newViewer(t, db), db, "someone else's todo")
+		// whole seconds apart, mysql stores datetimes without a fraction
+		start := time.Now().Truncate(time.Second).Add(-time.Hour)
+		for i := 0; i < 5; i++ {
+			_, err := db.Todo.Create().
+				SetText(fmt.Sprintf("todo %d", i)).
+				SetOwnerID(ownerID(ctx)).
+				SetUpdatedAt(start.Add(time.Duration(i) * time.Second)).
+				Save(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, order := range []string{"asc", "desc", "manual"} {
+			t.Run(order, func(t *testing.T) {
+				all, err := Query{Order: order}.Page(ctx, db.Todo.Query())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(all.Todos) != 5 || all.Next != "" {
+					t.Fatalf("got %d todos and next %q, want the viewer's 5 todos on one page", len(all.Todos), all.Next)
+				}
+
+				var paged []*models.Todo
+				query := Query{Order: order, Limit: 2}
+				for pages := 1; ; pages++ {
+					if pages > 3 {
+						t.Fatal("got more than 3 pages of 2 todos for 5 todos")
+					}
+					page, err := query.Page(ctx, db.Todo.Query())
+					if err != nil {
+						t.Fatal(err)
+					}
+					paged = append(paged, page.Todos...)
+					if page.Next == "" {
+						break
+					}
+					query.After = page.Next
+				}
+				if ids(paged) != ids(all.Todos) {
+					t.Errorf("paged todos %s, want %s", ids(paged), ids(all.Todos))
+				}
+			})
+		}
+
+		t.Run("insert before the cursor", func(t *testing.T) {
+			all, err := Query{Order: "desc"}.Page(ctx, db.Todo.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, err := Query{Order: "desc", Limit: 2}.Page(ctx, db.Todo.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			createTodo(t, ctx, db, "newest todo")
+			rest, err := Query{Order: "desc", After: first.Next}.Page(ctx, db.Todo.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(append(first.Todos, rest.Todos...)); got != ids(all.Todos) {
+				t.Errorf("paged todos %s, want %s", got, ids(all.Todos))
+			}
+		})
+
+		t.Run("filters", func(t *testing.T) {
+			milk := createTodo(t, ctx, db, "buy milk")
+			if _, err := milk.Update().SetStatus(todo.StatusDone).Save(ctx); err != nil {
+				t.Fatal(err)
+			}
+			for _, query := range []Query{{Search: "mil"}, {Search: "MILK buy"}, {Status: "done"}} {
+				page, err := query.Page(ctx, db.Todo.Query())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ids(page.Todos) != milk.ID.String() {
+					t.Errorf("%+v matched %s, want %s", query, ids(page.Todos), milk.ID)
+				}
+			}
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			page, err := Query{Order: "asc", Limit: 1}.Page(ctx, db.Todo.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, query := range []Query{
+				{Order: "sideways"},
+				{Status: "someday"},
+				{CreatedAfter: "yesterday"},
+				{Order: "desc", After: page.Next},
+				{Order: "asc", After: "not a cursor"},
+			} {
+				if _, err := query.Page(ctx, db.Todo.Query()); !errors.Is(err, errInvalidQuery) {
+					t.Errorf("%+v: got %v, want %v", query, err, errInvalidQuery)
+				}
+			}
+		})
+	})
+}
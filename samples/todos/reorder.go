@@ -0,0 +1,68 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/position"
+
+	"github.com/google/uuid"
+)
+
+var errTodosReordered = errors.New("the todos were reordered meanwhile, reload and try again")
+
+// ReorderRequest moves the todo ID between PrevID and NextID, its new neighbours in the manual order.
+// PrevID is empty when the todo moves to the top of the list, NextID when it moves to the bottom.
+type ReorderRequest struct {
+	ID     string `json:"id"`
+	PrevID string `json:"prev_id,omitempty"`
+	NextID string `json:"next_id,omitempty"`
+}
+
+// Reorder moves a todo between its new neighbours. Only the position of the moved todo is updated.
+func Reorder(ctx context.Context, db *models.Client, req ReorderRequest) (*models.Todo, error) {
+	uid, err := uuid.Parse(req.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid todo id %q", req.ID)
+	}
+	var moved *models.Todo
+	err = withTx(ctx, db, func(tx *models.Tx) error {
+		prev, err := neighbourPosition(ctx, tx, req.PrevID)
+		if err != nil {
+			return err
+		}
+		next, err := neighbourPosition(ctx, tx, req.NextID)
+		if err != nil {
+			return err
+		}
+		p, err := position.Between(prev, next)
+		if errors.Is(err, position.ErrOrder) {
+			return errTodosReordered
+		}
+		if err != nil {
+			return err
+		}
+		moved, err = tx.Todo.UpdateOneID(uid).SetPosition(p).Save(ctx)
+		return err
+	})
+	return moved, err
+}
+
+func neighbourPosition(ctx context.Context, tx *models.Tx, id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid todo id %q", id)
+	}
+	t, err := tx.Todo.Get(ctx, uid)
+	if models.IsNotFound(err) {
+		return "", errTodosReordered
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.Position, nil
+}
@@ -0,0 +1,88 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"gomodest-template/samples/todos/gen/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func manualOrder(t *testing.T, ctx context.Context, db *models.Client) []*models.Todo {
+	t.Helper()
+	page, err := Query{Order: "manual"}.Page(ctx, db.Todo.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Todos
+}
+
+func TestReorder(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		a := createTodo(t, ctx, db, "first todo")
+		b := createTodo(t, ctx, db, "second todo")
+		c := createTodo(t, ctx, db, "third todo")
+		// new todos go on top
+		if got, want := ids(manualOrder(t, ctx, db)), ids([]*models.Todo{c, b, a}); got != want {
+			t.Fatalf("got order %s, want %s", got, want)
+		}
+
+		moves := []struct {
+			req  ReorderRequest
+			want []*models.Todo
+		}{
+			{ReorderRequest{ID: c.ID.String(), PrevID: a.ID.String()}, []*models.Todo{b, a, c}},
+			{ReorderRequest{ID: a.ID.String(), NextID: b.ID.String()}, []*models.Todo{a, b, c}},
+			{ReorderRequest{ID: c.ID.String(), PrevID: a.ID.String(), NextID: b.ID.String()}, []*models.Todo{a, c, b}},
+		}
+		for _, move := range moves {
+			if _, err := Reorder(ctx, db, move.req); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := ids(manualOrder(t, ctx, db)), ids(move.want); got != want {
+				t.Fatalf("%+v: got order %s, want %s", move.req, got, want)
+			}
+		}
+
+		// moving the last todo after the first over and over narrows the same gap, the keys grow
+		var longest string
+		for i := 0; i < 200; i++ {
+			order := manualOrder(t, ctx, db)
+			last := order[len(order)-1]
+			moved, err := Reorder(ctx, db, ReorderRequest{
+				ID:     last.ID.String(),
+				PrevID: order[0].ID.String(),
+				NextID: order[1].ID.String(),
+			})
+			if err != nil {
+				t.Fatalf("move %d: %v", i, err)
+			}
+			if got, want := ids(manualOrder(t, ctx, db)), ids([]*models.Todo{order[0], last, order[1]}); got != want {
+				t.Fatalf("move %d: got order %s, want %s", i, got, want)
+			}
+			if len(moved.Position) > len(longest) {
+				longest = moved.Position
+			}
+		}
+		t.Logf("longest position after 200 moves: %d characters", len(longest))
+
+		order := manualOrder(t, ctx, db)
+		for _, req := range []ReorderRequest{
+			{ID: order[0].ID.String(), PrevID: uuid.NewString()},
+			// neighbours out of order, e.g. moved meanwhile
+			{ID: order[0].ID.String(), PrevID: order[2].ID.String(), NextID: order[1].ID.String()},
+		} {
+			if _, err := Reorder(ctx, db, req); !errors.Is(err, errTodosReordered) {
+				t.Errorf("%+v: got %v, want %v", req, err, errTodosReordered)
+			}
+			if got, want := ids(manualOrder(t, ctx, db)), ids(order); got != want {
+				t.Errorf("%+v: a failed move changed the order to %s", req, got)
+			}
+		}
+		if _, err := Reorder(newViewer(t, db), db, ReorderRequest{ID: a.ID.String()}); !models.IsNotFound(err) {
+			t.Errorf("another viewer moved the todo: %v", err)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package rule
+
+import (
+	"context"
+
+	"entgo.io/ent/entql"
+
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/privacy"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"gomodest-template/samples/todos/gen/models/user"
+	"gomodest-template/samples/todos/viewer"
+)
+
+// DenyIfNoViewer denies queries and mutations without a viewer in the context.
+func DenyIfNoViewer() privacy.QueryMutationRule {
+	return privacy.ContextQueryMutationRule(func(ctx context.Context) error {
+		if _, ok := viewer.FromContext(ctx); !ok {
+			return privacy.Denyf("viewer is missing")
+		}
+		return privacy.Skip
+	})
+}
+
+// FilterSelf limits user queries to the viewer.
+func FilterSelf() privacy.QueryRule {
+	return privacy.FilterFunc(func(ctx context.Context, f privacy.Filter) error {
+		v, _ := viewer.FromContext(ctx)
+		uf, ok := f.(*models.UserFilter)
+		if !ok {
+			return privacy.Denyf("unexpected filter type %T", f)
+		}
+		uf.WhereID(entql.ValueEQ(v.ID))
+		return privacy.Skip
+	})
+}
+
+// FilterOwner limits todo queries, updates and deletes to the todos owned by the viewer.
+func FilterOwner() privacy.QueryMutationRule {
+	return privacy.FilterFunc(func(ctx context.Context, f privacy.Filter) error {
+		v, _ := viewer.FromContext(ctx)
+		tf, ok := f.(*models.TodoFilter)
+		if !ok {
+			return privacy.Denyf("unexpected filter type %T", f)
+		}
+		tf.WhereHasOwnerWith(user.ID(v.ID))
+		return privacy.Allow
+	})
+}
+
+// AllowIfCreatedByViewer allows creating a todo owned by the viewer and denies any other create.
+func AllowIfCreatedByViewer() privacy.MutationRule {
+	return privacy.TodoMutationRuleFunc(func(ctx context.Context, m *models.TodoMutation) error {
+		if !m.Op().Is(models.OpCreate) {
+			return privacy.Skip
+		}
+		v, _ := viewer.FromContext(ctx)
+		if owner, ok := m.OwnerID(); ok && owner == v.ID {
+			return privacy.Allow
+		}
+		return privacy.Denyf("todo must be owned by the viewer")
+	})
+}
+
+type skipSoftDeleteKey struct{}
+
+// SkipSoftDelete returns a context in which FilterSoftDeleted lets trashed todos through, e.g. to restore or purge them.
+func SkipSoftDelete(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipSoftDeleteKey{}, true)
+}
+
+// FilterSoftDeleted hides the todos in the trash from queries, updates and deletes, unless the context skips it.
+func FilterSoftDeleted() privacy.QueryMutationRule {
+	return privacy.FilterFunc(func(ctx context.Context, f privacy.Filter) error {
+		if skip, _ := ctx.Value(skipSoftDeleteKey{}).(bool); skip {
+			return privacy.Skip
+		}
+		tf, ok := f.(*models.TodoFilter)
+		if !ok {
+			return privacy.Denyf("unexpected filter type %T", f)
+		}
+		tf.Where(entql.FieldNil(todo.FieldDeletedAt))
+		return privacy.Skip
+	})
+}
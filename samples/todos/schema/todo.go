@@ -1,15 +1,26 @@
 package schema
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
 
+	"entgo.io/ent/dialect"
 	"entgo.io/ent/dialect/entsql"
 	"entgo.io/ent/schema"
 
 	"entgo.io/ent"
+	"entgo.io/ent/schema/edge"
 	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/hook"
+	"gomodest-template/samples/todos/gen/models/privacy"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"gomodest-template/samples/todos/position"
+	"gomodest-template/samples/todos/rule"
 )
 
 // Todo holds the schema definition for the Todo entity.
@@ -32,5 +43,82 @@ func (Todo) Fields() []ent.Field {
 			Values("todo", "inprogress", "done").Default("todo").Optional(),
 		field.Time("created_at").Immutable().Default(time.Now),
 		field.Time("updated_at").Default(time.Now).UpdateDefault(time.Now),
+		// position is the fractional index key of the todo in the manual order, see package position.
+		// Keys grow by a character every few moves into the same gap: mysql's default varchar(255) would
+		// overflow, a utf8mb4 varchar(1024) exceed the index size. Keys are ascii only.
+		field.String("position").Default("").
+			SchemaType(map[string]string{dialect.MySQL: "varchar(1024) CHARACTER SET ascii COLLATE ascii_bin"}),
+		// deleted_at moves the todo to the trash, rule.FilterSoftDeleted hides it from queries.
+		field.Time("deleted_at").Optional().Nillable(),
+		// version is incremented by the edits of UpdateVersion, e.g. of the text, edits based on an older version are
+		// rejected. Reordering, marking done, bulk edits and the trash don't change it.
+		field.Int("version").Default(1),
+	}
+}
+
+// Indexes of the Todo.
+func (Todo) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("position").Edges("owner"),
+	}
+}
+
+// Edges of the Todo.
+func (Todo) Edges() []ent.Edge {
+	return []ent.Edge{
+		// optional so todos created before accounts existed don't block the migration, they are visible to nobody.
+		edge.From("owner", User.Type).Ref("todos").Unique(),
+	}
+}
+
+// Hooks of the Todo.
+func (Todo) Hooks() []ent.Hook {
+	return []ent.Hook{
+		hook.On(positionFirst, ent.OpCreate),
+	}
+}
+
+// positionFirst puts a new todo at the top of the viewer's manual order unless it has a position.
+func positionFirst(next ent.Mutator) ent.Mutator {
+	return hook.TodoFunc(func(ctx context.Context, m *models.TodoMutation) (ent.Value, error) {
+		if p, ok := m.Position(); ok && p != "" {
+			return next.Mutate(ctx, m)
+		}
+		first, err := m.Client().Todo.Query().
+			Where(todo.PositionNEQ("")).
+			Order(models.Asc(todo.FieldPosition)).
+			Limit(1).
+			Select(todo.FieldPosition).
+			Strings(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var b string
+		if len(first) > 0 {
+			b = first[0]
+		}
+		p, err := position.Between("", b)
+		if err != nil {
+			return nil, err
+		}
+		m.SetPosition(p)
+		return next.Mutate(ctx, m)
+	})
+}
+
+// Policy scopes todos to their owner, the viewer of the context.
+func (Todo) Policy() ent.Policy {
+	return privacy.Policy{
+		Mutation: privacy.MutationPolicy{
+			rule.DenyIfNoViewer(),
+			rule.AllowIfCreatedByViewer(),
+			rule.FilterSoftDeleted(),
+			rule.FilterOwner(),
+		},
+		Query: privacy.QueryPolicy{
+			rule.DenyIfNoViewer(),
+			rule.FilterSoftDeleted(),
+			rule.FilterOwner(),
+		},
 	}
 }
@@ -4,41 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gomodest-template/samples/todos/gen/models"
 	"gomodest-template/samples/todos/gen/models/todo"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sourcegraph/jsonrpc2"
 )
 
+// CodeConflict is the json-rpc error code of an edit based on an older version of a todo, its data is the current todo.
+const CodeConflict int64 = 409
+
 type TodosJsonRpc2 struct {
 	DB *models.Client
 }
 
-type Query struct {
-	Offset int    `json:"offset"`
-	Limit  int    `json:"limit"`
-	Order  string `json:"order,omitempty"`
-}
-
 func (t *TodosJsonRpc2) List(ctx context.Context, params []byte) (interface{}, error) {
 	query := &Query{
-		Offset: 0,
-		Limit:  3,
+		Limit: 3,
 	}
 	time.Sleep(1 * time.Second)
-	err := json.NewDecoder(bytes.NewReader(params)).Decode(query)
-	todos, err := t.DB.Todo.
-		Query().
-		Offset(query.Offset).
-		Limit(query.Limit).
-		Order(models.Desc(todo.FieldUpdatedAt)).
-		All(ctx)
-	if err != nil {
-		return nil, err
+	if len(params) > 0 {
+		if err := json.NewDecoder(bytes.NewReader(params)).Decode(query); err != nil {
+			return nil, err
+		}
 	}
-	return todos, nil
+	return query.Page(ctx, t.DB.Todo.Query())
 }
 
 func (t *TodosJsonRpc2) Create(ctx context.Context, params []byte) (interface{}, error) {
@@ -54,6 +47,7 @@ func (t *TodosJsonRpc2) Create(ctx context.Context, params []byte) (interface{},
 	todo, err := t.DB.Todo.Create().
 		SetStatus(todo.StatusInprogress).
 		SetText(req.Text).
+		SetOwnerID(ownerID(ctx)).
 		Save(ctx)
 	if err != nil {
 		return nil, err
@@ -76,11 +70,17 @@ func (t *TodosJsonRpc2) Update(ctx context.Context, params []byte) (interface{},
 		return nil, fmt.Errorf("minimum text size is 4")
 	}
 
-	todo, err := t.DB.Todo.
-		UpdateOneID(uid).
-		SetUpdatedAt(time.Now()).
-		SetText(req.Text).
-		Save(ctx)
+	version, err := editVersion(req.Version, req.Overwrite)
+	if err != nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+	}
+	todo, err := EditText(ctx, t.DB, uid, version, req.Text)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		rpcErr := &jsonrpc2.Error{Code: CodeConflict, Message: ErrConflict.Error()}
+		rpcErr.SetError(conflict.Current)
+		return nil, rpcErr
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +99,7 @@ func (t *TodosJsonRpc2) Delete(ctx context.Context, params []byte) (interface{},
 	if err != nil {
 		return nil, err
 	}
-	err = t.DB.Todo.DeleteOneID(uid).Exec(ctx)
+	err = SoftDelete(ctx, t.DB, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -127,3 +127,12 @@ func (t *TodosJsonRpc2) Get(ctx context.Context, params []byte) (interface{}, er
 
 	return todo, nil
 }
+
+func (t *TodosJsonRpc2) Reorder(ctx context.Context, params []byte) (interface{}, error) {
+	req := new(ReorderRequest)
+	err := json.NewDecoder(bytes.NewReader(params)).Decode(req)
+	if err != nil {
+		return nil, err
+	}
+	return Reorder(ctx, t.DB, *req)
+}
@@ -0,0 +1,66 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"gomodest-template/samples/todos/rule"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// undoLifetime is how long the undo of a delete is offered, the todo stays in the trash afterwards.
+const undoLifetime = 5 * time.Second
+
+var errTodoNotFound = errors.New("todo not found")
+
+// SoftDelete moves a todo to the trash. An UpdateOne would read the todo back through the soft delete filter
+// and miss it, hence the update of the todo's id while not deleted.
+func SoftDelete(ctx context.Context, db *models.Client, id uuid.UUID) error {
+	n, err := db.Todo.Update().
+		Where(todo.ID(id), todo.DeletedAtIsNil()).
+		SetDeletedAt(time.Now()).
+		Save(rule.SkipSoftDelete(ctx))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errTodoNotFound
+	}
+	return nil
+}
+
+// Trash lists the todos in the trash of the viewer, last deleted first.
+func Trash(ctx context.Context, db *models.Client) ([]*models.Todo, error) {
+	return db.Todo.Query().
+		Where(todo.DeletedAtNotNil()).
+		Order(models.Desc(todo.FieldDeletedAt, todo.FieldID)).
+		All(rule.SkipSoftDelete(ctx))
+}
+
+// Restore takes a todo out of the trash.
+func Restore(ctx context.Context, db *models.Client, id uuid.UUID) (*models.Todo, error) {
+	ctx = rule.SkipSoftDelete(ctx)
+	t, err := db.Todo.Query().Where(todo.ID(id), todo.DeletedAtNotNil()).Only(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Update().ClearDeletedAt().Save(ctx)
+}
+
+// Purge deletes a todo in the trash permanently.
+func Purge(ctx context.Context, db *models.Client, id uuid.UUID) error {
+	_, err := db.Todo.Delete().
+		Where(todo.ID(id), todo.DeletedAtNotNil()).
+		Exec(rule.SkipSoftDelete(ctx))
+	return err
+}
+
+// EmptyTrash deletes the todos in the trash of the viewer permanently and returns how many were deleted.
+func EmptyTrash(ctx context.Context, db *models.Client) (int, error) {
+	return db.Todo.Delete().
+		Where(todo.DeletedAtNotNil()).
+		Exec(rule.SkipSoftDelete(ctx))
+}
@@ -0,0 +1,84 @@
+package todos
+
+import (
+	"errors"
+	"gomodest-template/samples/todos/gen/models"
+	"testing"
+)
+
+func TestTrash(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		other := newViewer(t, db)
+		td := createTodo(t, ctx, db, "trashed todo")
+
+		if err := SoftDelete(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := SoftDelete(ctx, db, td.ID); !errors.Is(err, errTodoNotFound) {
+			t.Errorf("deleting twice: got %v, want %v", err, errTodoNotFound)
+		}
+		if n := countTodos(t, ctx, db); n != 0 {
+			t.Errorf("got %d todos, want the trashed one hidden", n)
+		}
+		trash, err := Trash(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids(trash) != td.ID.String() || trash[0].DeletedAt == nil {
+			t.Errorf("got trash %s, want %s", ids(trash), td.ID)
+		}
+
+		if trash, err := Trash(other, db); err != nil || len(trash) != 0 {
+			t.Errorf("another viewer got %d todos in the trash: %v", len(trash), err)
+		}
+		if _, err := Restore(other, db, td.ID); !models.IsNotFound(err) {
+			t.Errorf("another viewer restored the todo: %v", err)
+		}
+		if err := Purge(other, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		restored, err := Restore(ctx, db, td.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.DeletedAt != nil || countTodos(t, ctx, db) != 1 {
+			t.Errorf("restored todo still in the trash")
+		}
+		// only todos in the trash are purged
+		if err := Purge(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		if n := countTodos(t, ctx, db); n != 1 {
+			t.Errorf("purged a todo out of the trash")
+		}
+
+		if err := SoftDelete(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := Purge(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Restore(ctx, db, td.ID); !models.IsNotFound(err) {
+			t.Errorf("restored a purged todo: %v", err)
+		}
+
+		for _, text := range []string{"first todo", "second todo"} {
+			if err := SoftDelete(ctx, db, createTodo(t, ctx, db, text).ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		kept := createTodo(t, other, db, "another viewer's todo")
+		if err := SoftDelete(other, db, kept.ID); err != nil {
+			t.Fatal(err)
+		}
+		n, err := EmptyTrash(ctx, db)
+		if err != nil || n != 2 {
+			t.Errorf("emptied %d todos: %v, want 2", n, err)
+		}
+		if trash, err := Trash(other, db); err != nil || len(trash) != 1 {
+			t.Errorf("emptied another viewer's trash: %d todos left: %v", len(trash), err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package todos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrConflict rejects an edit based on an older version of a todo.
+var ErrConflict = errors.New("the todo was changed meanwhile, overwrite it or keep the other change")
+
+// ConflictError carries the todo as changed by the other edit.
+type ConflictError struct {
+	Current *models.Todo
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("todo %s is at version %d", e.Current.ID, e.Current.Version)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Version of a todo an edit is based on. Forms send it as a string.
+type Version int
+
+func (v *Version) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*v = Version(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*v = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", s, err)
+	}
+	*v = Version(n)
+	return nil
+}
+
+// UpdateVersion applies update to a todo if it's still at version and increments the version. Version 0 overwrites
+// any version, it's only passed for an explicit overwrite, e.g. If-Match: *, see editVersion.
+// It returns a *ConflictError with the current todo otherwise.
+func UpdateVersion(ctx context.Context, db *models.Client, id uuid.UUID, version int,
+	update func(u *models.TodoUpdate)) (*models.Todo, error) {
+	var updated *models.Todo
+	err := withTx(ctx, db, func(tx *models.Tx) error {
+		u := tx.Todo.Update().Where(todo.ID(id)).SetUpdatedAt(time.Now()).AddVersion(1)
+		if version != 0 {
+			u.Where(todo.Version(version))
+		}
+		update(u)
+		n, err := u.Save(ctx)
+		if err != nil {
+			return err
+		}
+		current, err := tx.Todo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &ConflictError{Current: current}
+		}
+		updated = current
+		return nil
+	})
+	return updated, err
+}
+
+// EditText sets the text of a todo if it's still at version, see UpdateVersion.
+func EditText(ctx context.Context, db *models.Client, id uuid.UUID, version int, text string) (*models.Todo, error) {
+	return UpdateVersion(ctx, db, id, version, func(u *models.TodoUpdate) {
+		u.SetText(text)
+	})
+}
+
+// errVersionRequired rejects an rpc or live edit without a version, it would silently overwrite any other change.
+var errVersionRequired = errors.New("version required, the version of the todo the edit is based on or overwrite to overwrite any version")
+
+// editVersion returns the version an edit is based on, 0 with overwrite. It returns errVersionRequired without either.
+func editVersion(version Version, overwrite bool) (int, error) {
+	if overwrite {
+		return 0, nil
+	}
+	if version < 1 {
+		return 0, errVersionRequired
+	}
+	return int(version), nil
+}
+
+// ETag is the entity tag of a todo's version.
+func ETag(t *models.Todo) string {
+	return strconv.Quote(strconv.Itoa(t.Version))
+}
+
+// errIfMatchRequired rejects an api edit without If-Match, it would silently overwrite any other change.
+var errIfMatchRequired = errors.New("If-Match required, the ETag of the todo or * to overwrite any version")
+
+// versionOfIfMatch returns the version of an If-Match header, 0 for *. It returns errIfMatchRequired without one.
+func versionOfIfMatch(ifMatch string) (int, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		return 0, errIfMatchRequired
+	}
+	if ifMatch == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(strings.TrimPrefix(ifMatch, "W/"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid If-Match %s: %w", ifMatch, err)
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match %s: not a todo version", ifMatch)
+	}
+	return version, nil
+}
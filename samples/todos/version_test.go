@@ -0,0 +1,134 @@
+package todos
+
+import (
+	"errors"
+	"gomodest-template/samples/todos/gen/models"
+	"gomodest-template/samples/todos/gen/models/todo"
+	"testing"
+)
+
+func TestUpdateVersion(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		td := createTodo(t, ctx, db, "first text")
+		if td.Version != 1 {
+			t.Fatalf("created at version %d, want 1", td.Version)
+		}
+
+		edited, err := EditText(ctx, db, td.ID, 1, "second text")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edited.Version != 2 || edited.Text != "second text" {
+			t.Errorf("got %q at version %d, want %q at version 2", edited.Text, edited.Version, "second text")
+		}
+
+		_, err = EditText(ctx, db, td.ID, 1, "stale text")
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+			t.Fatalf("editing a stale version: got %v, want a *ConflictError", err)
+		}
+		if conflict.Current.Version != 2 || conflict.Current.Text != "second text" {
+			t.Errorf("conflict with %q at version %d, want the second text at version 2",
+				conflict.Current.Text, conflict.Current.Version)
+		}
+
+		// version 0 overwrites any version
+		forced, err := UpdateVersion(ctx, db, td.ID, 0, func(u *models.TodoUpdate) {
+			u.SetStatus(todo.StatusDone)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if forced.Version != 3 || forced.Status != todo.StatusDone || forced.Text != "second text" {
+			t.Errorf("got %q %s at version %d, want the second text done at version 3",
+				forced.Text, forced.Status, forced.Version)
+		}
+
+		_, err = EditText(newViewer(t, db), db, td.ID, 3, "another viewer's text")
+		if !models.IsNotFound(err) || errors.Is(err, ErrConflict) {
+			t.Errorf("another viewer's edit: got %v, want not found", err)
+		}
+	})
+}
+
+func TestVersionOnlyChangedByEdits(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *models.Client) {
+		ctx := newViewer(t, db)
+		other := createTodo(t, ctx, db, "other todo")
+		td := createTodo(t, ctx, db, "first text")
+
+		// an edit based on version 1 isn't rejected by a move, a done mark or a trip through the trash
+		if _, err := Reorder(ctx, db, ReorderRequest{ID: td.ID.String(), PrevID: other.ID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToggleDone(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MarkAllDone(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+		if err := SoftDelete(ctx, db, td.ID); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := Restore(ctx, db, td.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.Version != 1 {
+			t.Fatalf("moved, marked done and restored at version %d, want 1", restored.Version)
+		}
+		edited, err := EditText(ctx, db, td.ID, 1, "second text")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edited.Version != 2 {
+			t.Errorf("edited at version %d, want 2", edited.Version)
+		}
+	})
+}
+
+func TestEditVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version   Version
+		overwrite bool
+		want      int
+		err       error
+	}{
+		{3, false, 3, nil},
+		{3, true, 0, nil},
+		{0, true, 0, nil},
+		{0, false, 0, errVersionRequired},
+		{-1, false, 0, errVersionRequired},
+	} {
+		version, err := editVersion(tc.version, tc.overwrite)
+		if version != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("%d, overwrite %v: got %d, %v, want %d, %v", tc.version, tc.overwrite, version, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestVersionOfIfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		ifMatch string
+		version int
+		err     bool
+	}{
+		{`"3"`, 3, false},
+		{`W/"3"`, 3, false},
+		{` "12" `, 12, false},
+		{"*", 0, false},
+		{"", 0, true},
+		{"3", 0, true},
+		{`"0"`, 0, true},
+		{`"three"`, 0, true},
+	} {
+		version, err := versionOfIfMatch(tc.ifMatch)
+		if version != tc.version || (err != nil) != tc.err {
+			t.Errorf("%q: got %d, %v", tc.ifMatch, version, err)
+		}
+	}
+	if _, err := versionOfIfMatch(""); !errors.Is(err, errIfMatchRequired) {
+		t.Errorf("got %v, want %v", err, errIfMatchRequired)
+	}
+}
@@ -0,0 +1,26 @@
+package viewer
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Viewer is the logged in user making a request.
+type Viewer struct {
+	ID    uuid.UUID
+	Email string
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying v.
+func NewContext(ctx context.Context, v Viewer) context.Context {
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// FromContext returns the viewer of ctx if any.
+func FromContext(ctx context.Context) (Viewer, bool) {
+	v, ok := ctx.Value(ctxKey{}).(Viewer)
+	return v, ok
+}
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"gomodest-template/samples/todos/gen/models"
 	"gomodest-template/samples/todos/gen/models/todo"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/rpc"
 	"net/rpc/jsonrpc"
@@ -20,14 +20,20 @@ type Params struct {
 }
 
 type TodoRequest struct {
-	ID       string `json:"id"`
-	Text     string `json:"text"`
-	Redirect bool   `json:"redirect,omitempty"`
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Version the edit is based on, required unless Overwrite is set.
+	Version Version `json:"version,omitempty"`
+	// Overwrite edits the todo whatever its version, like If-Match: *.
+	Overwrite bool `json:"overwrite,omitempty"`
+	Redirect  bool `json:"redirect,omitempty"`
 }
 
 type Todos struct {
 	DB  *models.Client
 	Ctx context.Context
+	// Logger has the attributes of the connection and its user.
+	Logger *slog.Logger
 }
 
 func (t *Todos) list() ([]byte, error) {
@@ -44,7 +50,7 @@ func (t *Todos) list() ([]byte, error) {
 }
 
 func (t *Todos) List(_ []struct{}, reply *Params) error {
-	log.Println("Todos.List called")
+	t.Logger.Debug("rpc call", "method", "Todos.List")
 	data, err := t.list()
 	if err != nil {
 		return err
@@ -54,10 +60,11 @@ func (t *Todos) List(_ []struct{}, reply *Params) error {
 }
 
 func (t *Todos) Add(req TodoRequest, reply *Params) error {
-	log.Println("Todos.Add called")
+	t.Logger.Debug("rpc call", "method", "Todos.Add")
 	t.DB.Todo.Create().
 		SetStatus(todo.StatusInprogress).
 		SetText(req.Text).
+		SetOwnerID(ownerID(t.Ctx)).
 		Save(t.Ctx)
 
 	data, err := t.list()
@@ -69,13 +76,13 @@ func (t *Todos) Add(req TodoRequest, reply *Params) error {
 }
 
 func (t *Todos) Delete(req TodoRequest, reply *Params) error {
-	log.Println("Todos.Delete called")
+	t.Logger.Debug("rpc call", "method", "Todos.Delete")
 	uid, err := uuid.Parse(req.ID)
 	if err != nil {
 		return err
 	}
 
-	err = t.DB.Todo.DeleteOneID(uid).Exec(t.Ctx)
+	err = SoftDelete(t.Ctx, t.DB, uid)
 	if err != nil {
 		return err
 	}
@@ -88,10 +95,31 @@ func (t *Todos) Delete(req TodoRequest, reply *Params) error {
 	return nil
 }
 
-func StartRPCServer(db *models.Client, ctx context.Context) {
-	rpc.Register(&Todos{DB: db, Ctx: ctx})
-	http.Handle("/", websocket.Handler(func(conn *websocket.Conn) {
-		jsonrpc.ServeConn(conn)
+// StartRPCServer serves the net/rpc todos on addr. Shutdown the returned server to stop it.
+// Every connection is served the todos of the viewer returned by viewerContext.
+func StartRPCServer(db *models.Client, viewerContext func(r *http.Request) (context.Context, bool), addr string,
+	logger *slog.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/", websocket.Handler(func(conn *websocket.Conn) {
+		connLogger := logger.With("remote_addr", conn.Request().RemoteAddr)
+		ctx, ok := viewerContext(conn.Request())
+		if !ok {
+			connLogger.Debug("rejected rpc connection without a viewer")
+			conn.Close()
+			return
+		}
+		connLogger = connLogger.With("user_id", ownerID(ctx))
+		connLogger.Debug("rpc connection")
+		server := rpc.NewServer()
+		server.Register(&Todos{DB: db, Ctx: ctx, Logger: connLogger})
+		server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		connLogger.Debug("rpc connection closed")
 	}))
-	go http.ListenAndServe("localhost:3001", nil)
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Warn("rpc server err", "err", err)
+		}
+	}()
+	return srv
 }
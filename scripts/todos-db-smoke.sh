@@ -0,0 +1,70 @@
+#!/usr/bin/env bash
+# Runs the todos handlers against the database selected by APP_DATABASE_DRIVER and APP_DATABASE_DSN:
+# applies the migrations, signs up two users, exercises the REST, turbo-frame and live pages as the first,
+# checks the second can't see their todos and rolls the migrations back.
+set -euo pipefail
+
+addr=${SMOKE_ADDR:-localhost:3099}
+dir=$(mktemp -d)
+bin=$dir/app
+export APP_ENV=dev APP_ADDR=$addr APP_RPC_ADDR=localhost:3098 APP_MIGRATIONS=refuse
+export APP_DATABASE_DSN=${APP_DATABASE_DSN:-"file:$dir/smoke.db?_pragma=foreign_keys(1)&_time_format=sqlite"}
+
+fail() {
+  echo "FAIL [${APP_DATABASE_DRIVER:-sqlite}]: $*" >&2
+  exit 1
+}
+
+CGO_ENABLED=0 go build -o "$bin" .
+"$bin" migrate up
+"$bin" migrate status | grep -q pending && fail "migrations left pending"
+
+"$bin" >"$bin.log" 2>&1 &
+pid=$!
+trap 'kill $pid 2>/dev/null || true; cat "$bin.log"' EXIT
+for _ in $(seq 50); do
+  curl -sf -o /dev/null "http://$addr/" && break
+  sleep 0.1
+done
+
+api=http://$addr/samples/api/todos
+curl -sf -o /dev/null "$api" && fail "anonymous list"
+signup() {
+  curl -sf -o /dev/null -c "$dir/$1.jar" "http://$addr/samples/signup" \
+    --data-urlencode "email=$1@example.com" --data-urlencode "password=smoke-password" || fail "signup $1"
+}
+signup alice
+signup bob
+alice=(-b "$dir/alice.jar")
+bob=(-b "$dir/bob.jar")
+
+todo=$(curl -sf -D "$dir/created" "${alice[@]}" -XPOST "$api" -d '{"text":"smoke"}') || fail "create"
+id=$(echo "$todo" | sed -E 's/.*"id":"([^"]+)".*/\1/')
+etag=$(tr -d '\r' <"$dir/created" | sed -n 's/^[Ee][Tt]ag: //p')
+curl -sf "${alice[@]}" "$api" | grep -q "\"id\":\"$id\"" || fail "list"
+curl -sf "${bob[@]}" "$api" | grep -q "$id" && fail "todo listed for another user"
+curl -sf -o /dev/null "${bob[@]}" -XDELETE "$api/$id" && fail "todo deleted by another user"
+[ "$(curl -s -o /dev/null -w '%{http_code}' "${alice[@]}" -XPUT "$api/$id/status" -d '{"status":"done"}')" = 428 ] ||
+  fail "update without If-Match"
+curl -sf "${alice[@]}" -H "If-Match: $etag" -XPUT "$api/$id/status" -d '{"status":"done"}' | grep -q '"status":"done"' ||
+  fail "update status"
+[ "$(curl -s -o /dev/null -w '%{http_code}' "${alice[@]}" -H "If-Match: $etag" -XPUT "$api/$id/text" -d '{"text":"stale"}')" = 412 ] ||
+  fail "update of a stale version"
+curl -sf "${alice[@]}" -H 'If-Match: *' -XPUT "$api/$id/text" -d '{"text":"smoked"}' | grep -q '"text":"smoked"' ||
+  fail "update text"
+curl -sf "${alice[@]}" "$api?q=smok&status=done" | grep -q "$id" || fail "search"
+curl -sf "${alice[@]}" "$api?q=nothing" | grep -q "$id" && fail "search matched another text"
+curl -sf -o /dev/null "${alice[@]}" -XPOST "http://$addr/samples/todos/$id/toggle" || fail "toggle"
+curl -sf "${alice[@]}" "$api?status=todo" | grep -q "$id" || fail "toggled status"
+curl -sf -o /dev/null "${alice[@]}" -XPOST "http://$addr/samples/todos/done" || fail "mark all done"
+curl -sf "${alice[@]}" "$api?status=done" | grep -q "$id" || fail "marked done"
+page=$(curl -sf "${alice[@]}" "http://$addr/samples/todos/list") && grep -q smoked <<<"$page" || fail "turbo-frame list"
+page=$(curl -sf "${alice[@]}" "http://$addr/samples/live/todos") && grep -q smoked <<<"$page" || fail "live list"
+curl -sf "${alice[@]}" -XDELETE "$api/$id" >/dev/null || fail "delete"
+curl -sf "${alice[@]}" "$api" | grep -q "$id" && fail "deleted todo listed"
+
+kill $pid
+wait $pid || true
+trap - EXIT
+"$bin" migrate down "$("$bin" migrate status | wc -l)"
+echo "ok [${APP_DATABASE_DRIVER:-sqlite}]"